@@ -0,0 +1,120 @@
+package dapgx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+)
+
+// Batch queues statements with xelf arguments to send them to the database in one round trip.
+// Statements are prepared using the connection cache and the arguments are encoded using the
+// parameter types of the prepared statements, like Query and Exec.
+type Batch struct {
+	items []batchItem
+}
+
+type batchItem struct {
+	sql  string
+	args []lit.Val
+	scan func(pgx.Rows) error
+}
+
+// Len returns the number of queued statements.
+func (b *Batch) Len() int { return len(b.items) }
+
+// Queue adds a statement whose result is only the command tag.
+func (b *Batch) Queue(sql string, args []lit.Val) {
+	b.items = append(b.items, batchItem{sql: sql, args: args})
+}
+
+// QueueOne adds a query whose single result is scanned into mut like ScanOne.
+func (b *Batch) QueueOne(reg lit.Regs, scal bool, mut lit.Mut, sql string, args []lit.Val) {
+	b.QueueRows(sql, args, func(rows pgx.Rows) error {
+		return ScanOne(reg, scal, mut, rows)
+	})
+}
+
+// QueueMany adds a query whose results are appended to mut like ScanMany.
+func (b *Batch) QueueMany(reg lit.Regs, scal bool, mut lit.Mut, sql string, args []lit.Val) {
+	b.QueueRows(sql, args, func(rows pgx.Rows) error {
+		return ScanMany(reg, scal, mut, rows)
+	})
+}
+
+// QueueRows adds a query whose result rows are passed to scan. The rows are closed afterwards.
+func (b *Batch) QueueRows(sql string, args []lit.Val, scan func(pgx.Rows) error) {
+	b.items = append(b.items, batchItem{sql, args, scan})
+}
+
+// BatchSender is implemented by pools, connections and transactions of pgx.
+type BatchSender interface {
+	SendBatch(context.Context, *pgx.Batch) pgx.BatchResults
+}
+
+// Send prepares and sends all queued statements using pc and returns the command tags of every
+// statement or an error. Query results are scanned before Send returns.
+func (b *Batch) Send(ctx context.Context, pc PC) ([]pgconn.CommandTag, error) {
+	bs, ok := pc.(BatchSender)
+	if !ok {
+		return nil, fmt.Errorf("batch not supported by %T", pc)
+	}
+	pb := &pgx.Batch{}
+	ends := make([]func(int64, error), len(b.items))
+	defer func() {
+		// end the traces of statements without result
+		for _, end := range ends {
+			if end != nil {
+				end(0, fmt.Errorf("batch aborted"))
+			}
+		}
+	}()
+	for i, it := range b.items {
+		_, ends[i] = StartTrace(ctx, it.sql, it.args)
+		name, wrap, err := prep(ctx, pc, it.sql, it.args)
+		if err != nil {
+			ends[i](0, err)
+			ends[i] = nil
+			return nil, fmt.Errorf("batch statement %d: %w", i, err)
+		}
+		pb.Queue(name, wrap...)
+	}
+	br := bs.SendBatch(ctx, pb)
+	defer br.Close()
+	tags := make([]pgconn.CommandTag, len(b.items))
+	for i, it := range b.items {
+		var err error
+		if it.scan == nil {
+			tags[i], err = br.Exec()
+		} else {
+			tags[i], err = batchScan(ctx, br, it.scan)
+		}
+		ends[i](tags[i].RowsAffected(), err)
+		ends[i] = nil
+		if err != nil {
+			if isStalePlan(err) {
+				invalidate(ctx, pc, it.sql)
+			}
+			return tags, fmt.Errorf("batch statement %d: %w", i, err)
+		}
+	}
+	return tags, br.Close()
+}
+
+func batchScan(ctx context.Context, br pgx.BatchResults, scan func(pgx.Rows) error) (pgconn.CommandTag, error) {
+	rows, err := br.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rows = withTypes(ctx, rows)
+	err = scan(rows)
+	if err == nil {
+		// the scanner may stop early, so we close the rows to read the tag and error
+		rows.Close()
+		err = rows.Err()
+	}
+	return rows.CommandTag(), err
+}
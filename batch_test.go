@@ -0,0 +1,69 @@
+package dapgx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+func TestBatch(t *testing.T) {
+	db := testDB(t, `drop table if exists dapgx_batch_test;
+		create table dapgx_batch_test (id int primary key, name text)`)
+	defer db.Close()
+	ctx := context.Background()
+	defer db.Exec(ctx, "drop table dapgx_batch_test")
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire %v", err)
+	}
+	defer conn.Release()
+	var reg lit.Regs
+	ins := "insert into dapgx_batch_test (id, name) values ($1, $2)"
+	b := &Batch{}
+	b.Queue(ins, []lit.Val{lit.Int(1), lit.Str("a")})
+	b.Queue(ins, []lit.Val{lit.Int(2), lit.Null{}})
+	ids := lit.NewList(typ.Int)
+	b.QueueMany(reg, true, ids, "select id from dapgx_batch_test order by id", nil)
+	var name lit.Str
+	b.QueueOne(reg, true, &name, "select name from dapgx_batch_test where id = $1",
+		[]lit.Val{lit.Int(1)})
+	tags, err := b.Send(ctx, conn.Conn())
+	if err != nil {
+		t.Fatalf("send %v", err)
+	}
+	if len(tags) != 4 || tags[0].RowsAffected() != 1 || tags[2].RowsAffected() != 2 {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if got := ids.String(); got != "[1 2]" {
+		t.Errorf("want ids [1 2] got %s", got)
+	}
+	if name != "a" {
+		t.Errorf("want name a got %s", name)
+	}
+	// a failing callback stops reading the results and reports the statement index
+	errScan := errors.New("scan failed")
+	b = &Batch{}
+	b.Queue(ins, []lit.Val{lit.Int(3), lit.Str("c")})
+	b.QueueRows("select id from dapgx_batch_test", nil, func(rows pgx.Rows) error {
+		return errScan
+	})
+	b.Queue(ins, []lit.Val{lit.Int(4), lit.Str("d")})
+	tags, err = b.Send(ctx, conn.Conn())
+	if !errors.Is(err, errScan) || !strings.HasPrefix(err.Error(), "batch statement 1:") {
+		t.Fatalf("want callback error for statement 1 got %v", err)
+	}
+	if len(tags) != 3 || tags[0].RowsAffected() != 1 {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	// the connection is still usable
+	var n int64
+	err = conn.QueryRow(ctx, "select count(*) from dapgx_batch_test").Scan(&n)
+	if err != nil {
+		t.Errorf("count after failed batch %v", err)
+	}
+}
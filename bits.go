@@ -0,0 +1,66 @@
+package dapgx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Bits is an integer literal with the bits type. We use it for postgres bit strings, that are
+// read as binary number, the same way postgres casts bit strings to integers. The bit length
+// is kept, so that values of fixed width bit(n) columns can be written back.
+type Bits struct {
+	lit.Int
+	Width int // bit length or zero for the shortest bit string
+}
+
+func (b Bits) Type() typ.Type { return typ.Type{Kind: knd.Bits} }
+func (b Bits) Value() lit.Val { return b }
+func (b Bits) Mut() lit.Mut   { return &b }
+
+// BitString returns the bit string for b padded to its width.
+func (b Bits) BitString() string { return formatBitString(uint64(b.Int), b.Width) }
+
+func (b *Bits) New() lit.Mut     { return new(Bits) }
+func (b *Bits) Ptr() interface{} { return b }
+func (b *Bits) Assign(v lit.Val) error {
+	if v == nil || v.Nil() {
+		*b = Bits{}
+		return nil
+	}
+	if o, ok := lit.Unwrap(v).(Bits); ok {
+		*b = o
+		return nil
+	}
+	n, err := lit.ToInt(v)
+	if err != nil {
+		return err
+	}
+	b.Int = n
+	return nil
+}
+
+// parseBitString returns bits for a text bit string like '0101' or an error.
+func parseBitString(s string) (Bits, error) {
+	if len(s) > 64 {
+		return Bits{}, fmt.Errorf("bit string length %d exceeds 64", len(s))
+	}
+	n, err := strconv.ParseUint(s, 2, 64)
+	if err != nil && s != "" {
+		return Bits{}, fmt.Errorf("invalid bit string %q", s)
+	}
+	return Bits{lit.Int(n), len(s)}, nil
+}
+
+// formatBitString returns the bit string for v left-padded with zeros to width or the shortest.
+func formatBitString(v uint64, width int) string {
+	s := strconv.FormatUint(v, 2)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package dapgx
+
+import (
+	"strings"
+	"sync"
+)
+
+// Codec is a custom codec for a postgres type, usually one of an extension like ltree or PostGIS.
+// Custom types are usually sent in text format, a missing binary decoder reports an error.
+// The encoder may be nil to use the default encoder for the type.
+type Codec struct {
+	DecoderPair
+	Encoder EncoderFunc
+}
+
+var codecs struct {
+	sync.RWMutex
+	oids  map[uint32]*Codec
+	names map[string]*Codec
+}
+
+// RegisterCodec registers decoders and an encoder for the postgres type oid. Registered codecs
+// take precedence over the built-in codecs and are used by Scanner as well as Query and Exec.
+func RegisterCodec(oid uint32, decs DecoderPair, enc EncoderFunc) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	if codecs.oids == nil {
+		codecs.oids = make(map[uint32]*Codec)
+	}
+	codecs.oids[oid] = &Codec{decs, enc}
+}
+
+// RegisterTypeCodec registers decoders and an encoder for the postgres type name. The name is
+// either qualified like 'public.ltree' or unqualified. Extension types have no fixed oid, so the
+// name is resolved to the type oid of each database using the Types registry.
+func RegisterTypeCodec(name string, decs DecoderPair, enc EncoderFunc) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	if codecs.names == nil {
+		codecs.names = make(map[string]*Codec)
+	}
+	codecs.names[name] = &Codec{decs, enc}
+}
+
+// UnregisterCodec removes the codec registered for the postgres type oid.
+func UnregisterCodec(oid uint32) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	delete(codecs.oids, oid)
+}
+
+// UnregisterTypeCodec removes the codec registered for the postgres type name.
+func UnregisterTypeCodec(name string) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	delete(codecs.names, name)
+}
+
+// LookupCodec returns the codec registered for oid or nil.
+func LookupCodec(oid uint32) *Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.oids[oid]
+}
+
+// LookupTypeCodec returns the codec registered for the qualified type name or nil. It falls
+// back to codecs registered with the unqualified name.
+func LookupTypeCodec(name string) *Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if c := codecs.names[name]; c != nil {
+		return c
+	}
+	if idx := strings.LastIndexByte(name, '.'); idx >= 0 {
+		return codecs.names[name[idx+1:]]
+	}
+	return nil
+}
+
+// lookupDecs returns the registered or built-in decoders for oid.
+func lookupDecs(oid uint32) (DecoderPair, bool) {
+	if c := LookupCodec(oid); c != nil && (c.Text != nil || c.Binary != nil) {
+		return c.decoders(), true
+	}
+	decs, ok := decmap[oid]
+	return decs, ok
+}
+
+func (c *Codec) decoders() DecoderPair {
+	decs := c.DecoderPair
+	if decs.Text == nil {
+		decs.Text = errDecoder
+	}
+	if decs.Binary == nil {
+		decs.Binary = errDecoder
+	}
+	return decs
+}
@@ -0,0 +1,114 @@
+package dapgx
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"xelf.org/xelf/lit"
+)
+
+// Attr is a named attribute of a composite type.
+type Attr struct {
+	Name string
+	Oid  uint32
+}
+
+// compositeDecs returns decoders for composite values with the attributes attrs into objects.
+// Anonymous records have no attributes, their fields are named f1, f2 and so on, like postgres
+// names the columns of row expressions. The text decoder decodes record fields as strings.
+func compositeDecs(ts *Types, attrs []Attr) DecoderPair {
+	return DecoderPair{compositeTextDec(ts, attrs), compositeBinDec(ts, attrs)}
+}
+func compositeTextDec(ts *Types, attrs []Attr) Decoder {
+	return func(raw []byte) (lit.Val, error) {
+		s := pgtype.NewCompositeTextScanner(nil, raw)
+		var kvs lit.Keyed
+		for i := 0; s.Next(); i++ {
+			key, oid := attrKey(attrs, i)
+			val, err := decodeAttr(ts, oid, false, s.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("decode attr %s: %w", key, err)
+			}
+			kvs = append(kvs, lit.KeyVal{Key: key, Val: val})
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return lit.MakeObj(kvs), nil
+	}
+}
+func compositeBinDec(ts *Types, attrs []Attr) Decoder {
+	return func(raw []byte) (lit.Val, error) {
+		s := pgtype.NewCompositeBinaryScanner(nil, raw)
+		kvs := make(lit.Keyed, 0, s.FieldCount())
+		for i := 0; s.Next(); i++ {
+			key, _ := attrKey(attrs, i)
+			val, err := decodeAttr(ts, s.OID(), true, s.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("decode attr %s: %w", key, err)
+			}
+			kvs = append(kvs, lit.KeyVal{Key: key, Val: val})
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return lit.MakeObj(kvs), nil
+	}
+}
+
+func attrKey(attrs []Attr, i int) (string, uint32) {
+	if i < len(attrs) {
+		return attrs[i].Name, attrs[i].Oid
+	}
+	return fmt.Sprintf("f%d", i+1), pgtype.TextOID
+}
+
+func decodeAttr(ts *Types, oid uint32, bin bool, raw []byte) (lit.Val, error) {
+	if raw == nil {
+		return lit.Null{}, nil
+	}
+	return ts.FieldDecoder(oid, bin)(raw)
+}
+
+// WrapComposite encodes an object for a composite type with the attributes Attrs.
+// Attributes missing from the object are encoded as null.
+type WrapComposite struct {
+	lit.Keyr
+	Attrs []Attr
+	Types *Types
+}
+
+func (w WrapComposite) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	cb := pgtype.NewCompositeTextBuilder(ci, b)
+	for _, a := range w.Attrs {
+		enc, err := w.attrEncoder(a)
+		if err != nil {
+			return nil, err
+		}
+		cb.AppendEncoder(enc)
+	}
+	return cb.Finish()
+}
+func (w WrapComposite) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	cb := pgtype.NewCompositeBinaryBuilder(ci, b)
+	for _, a := range w.Attrs {
+		enc, err := w.attrEncoder(a)
+		if err != nil {
+			return nil, err
+		}
+		cb.AppendEncoder(a.Oid, enc)
+	}
+	return cb.Finish()
+}
+
+func (w WrapComposite) attrEncoder(a Attr) (Encoder, error) {
+	val, err := w.Key(a.Name)
+	if err != nil {
+		val = nil
+	}
+	enc, err := w.Types.FieldEncoder(a.Oid, val)
+	if err != nil {
+		return nil, fmt.Errorf("encode attr %s: %w", a.Name, err)
+	}
+	return enc, nil
+}
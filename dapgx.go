@@ -5,6 +5,8 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"io"
+	"math/rand"
+	"time"
 
 	"github.com/jackc/pgconn"
 	pgx "github.com/jackc/pgx/v4"
@@ -27,12 +29,19 @@ type PC interface {
 var _ C = (*pgxpool.Pool)(nil)
 var _ PC = (*pgx.Conn)(nil)
 var _ PC = (pgx.Tx)(nil)
+var _ DB = (pgx.Tx)(nil)
 
 type DB interface {
 	Begin(context.Context) (pgx.Tx, error)
 }
 
+// WithTx calls f with a new transaction from db, that is committed if f returns no error.
+// If db is itself a pgx.Tx, f is called in a savepoint instead, that is rolled back on error
+// and released on success. This lets helpers using WithTx compose in a larger transaction.
 func WithTx(ctx context.Context, db DB, f func(PC) error) error {
+	if tx, ok := db.(pgx.Tx); ok {
+		return withSavepoint(ctx, tx, f)
+	}
 	tx, err := db.Begin(ctx)
 	if err != nil {
 		return err
@@ -45,8 +54,107 @@ func WithTx(ctx context.Context, db DB, f func(PC) error) error {
 	return tx.Commit(ctx)
 }
 
+func withSavepoint(ctx context.Context, tx pgx.Tx, f func(PC) error) error {
+	// pgx begins a pseudo nested transaction using a savepoint
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(ctx)
+	err = f(sp)
+	if err != nil {
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+// TxDB is a database that can begin transactions with options.
+type TxDB interface {
+	DB
+	BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Retry is a retry policy for transactions that failed with a serialization failure or deadlock.
+type Retry struct {
+	Max     int           // max attempts, zero or one to never retry
+	Backoff time.Duration // backoff before the second attempt, doubled for each further attempt
+	Limit   time.Duration // optional upper limit for the backoff
+}
+
+// DefaultRetry is a reasonable retry policy for serializable transactions.
+var DefaultRetry = Retry{Max: 5, Backoff: 10 * time.Millisecond, Limit: time.Second}
+
+// SnapshotTx are the options for read-only transactions with a consistent snapshot.
+var SnapshotTx = pgx.TxOptions{
+	IsoLevel:       pgx.RepeatableRead,
+	AccessMode:     pgx.ReadOnly,
+	DeferrableMode: pgx.Deferrable,
+}
+
+// WithTxOpts is like WithTx but begins the transaction with opts and retries the whole
+// transaction, including f, for serialization failures and deadlocks as allowed by retry.
+func WithTxOpts(ctx context.Context, db TxDB, opts pgx.TxOptions, retry Retry, f func(PC) error) error {
+	for n := 1; ; n++ {
+		err := withTx(ctx, db, opts, f)
+		if err == nil || n >= retry.Max || !IsRetryable(err) {
+			return err
+		}
+		t := time.NewTimer(retry.backoff(n))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+func withTx(ctx context.Context, db TxDB, opts pgx.TxOptions, f func(PC) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	err = f(tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// backoff returns the jittered backoff after attempt n.
+func (r Retry) backoff(n int) time.Duration {
+	d := r.Backoff << uint(n-1)
+	if r.Limit > 0 && (d > r.Limit || d <= 0) {
+		d = r.Limit
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// IsRetryable returns whether err is a serialization failure or deadlock, that can be resolved
+// by retrying the transaction.
+func IsRetryable(err error) bool {
+	return isPgCode(err, "40001") || isPgCode(err, "40P01")
+}
+
+var _ TxDB = (*pgxpool.Pool)(nil)
+var _ TxDB = (*pgx.Conn)(nil)
+
+// Open returns a new connection pool for dsn or an error. The optional logger is used by pgx for
+// connection level logs. Use SetTracer to trace the statements.
 func Open(ctx context.Context, dsn string, logger pgx.Logger) (*pgxpool.Pool, error) {
-	db, err := pgxpool.Connect(ctx, dsn)
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing pgx pool config: %w", err)
+	}
+	if logger != nil {
+		cfg.ConnConfig.Logger = logger
+	}
+	cfg.AfterRelease = PruneStmtCaches
+	db, err := pgxpool.ConnectConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("creating pgx connection pool: %w", err)
 	}
@@ -58,7 +166,38 @@ func Open(ctx context.Context, dsn string, logger pgx.Logger) (*pgxpool.Pool, er
 	return db, nil
 }
 
+// Query prepares sql with args and returns the resulting rows or an error.
+// The type registry attached to ctx is used to encode args and is carried with the rows.
+// Prepared statements are cached per connection and prepared again if invalidated by ddl.
+// Database errors are returned as is, use TranslateErr for typed constraint errors.
 func Query(ctx context.Context, pc PC, sql string, args []lit.Val) (pgx.Rows, error) {
+	ctx, end := StartTrace(ctx, sql, args)
+	rows, err := query(ctx, pc, sql, args)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	var res pgx.Rows = &stmtRows{Rows: rows, ctx: ctx, pc: pc, sql: sql, args: args}
+	res = &traceRows{Rows: res, end: end}
+	return withTypes(ctx, res), nil
+}
+
+// Exec prepares and executes sql with args or returns an error.
+// Database errors are returned as is, use TranslateErr for typed constraint errors.
+func Exec(ctx context.Context, pc PC, sql string, args []lit.Val) error {
+	ctx, end := StartTrace(ctx, sql, args)
+	tag, err := exec(ctx, pc, sql, args)
+	if isStalePlan(err) {
+		invalidate(ctx, pc, sql)
+		if canRetry(pc) {
+			tag, err = exec(ctx, pc, sql, args)
+		}
+	}
+	end(tag.RowsAffected(), err)
+	return err
+}
+
+func query(ctx context.Context, pc PC, sql string, args []lit.Val) (pgx.Rows, error) {
 	name, wrap, err := prep(ctx, pc, sql, args)
 	if err != nil {
 		return nil, err
@@ -66,33 +205,36 @@ func Query(ctx context.Context, pc PC, sql string, args []lit.Val) (pgx.Rows, er
 	return pc.Query(ctx, name, wrap...)
 }
 
-func Exec(ctx context.Context, pc PC, sql string, args []lit.Val) error {
+func exec(ctx context.Context, pc PC, sql string, args []lit.Val) (pgconn.CommandTag, error) {
 	name, wrap, err := prep(ctx, pc, sql, args)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = pc.Exec(ctx, name, wrap...)
-	return err
+	return pc.Exec(ctx, name, wrap...)
 }
 
 func prep(ctx context.Context, pc PC, sql string, args []lit.Val) (string, []interface{}, error) {
-	name := hashSql(sql)
-	sd, err := pc.Prepare(ctx, name, sql)
+	sd, err := prepare(ctx, pc, sql)
 	if err != nil {
 		return "", nil, err
 	}
 	if len(sd.ParamOIDs) != len(args) {
 		return "", nil, fmt.Errorf("invalid number of params")
 	}
+	ts, to := TypesFrom(ctx), TimeOptsFrom(ctx)
 	res := make([]interface{}, len(args))
 	for i, oid := range sd.ParamOIDs {
-		enc, err := FieldEncoder(oid, args[i])
+		if oid == 0 {
+			// the parameter type is unknown, for example with the database/sql adapter
+			oid = argOid(args[i])
+		}
+		enc, err := ts.FieldEncoder(oid, to.convert(ts.Resolve(oid), args[i]))
 		if err != nil {
 			return "", nil, err
 		}
 		res[i] = enc
 	}
-	return name, res, nil
+	return sd.Name, res, nil
 }
 
 func hashSql(sql string) string {
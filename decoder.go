@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -25,11 +26,11 @@ type Decoder func([]byte) (lit.Val, error)
 
 // FieldDecoder returns a decoder for the given field description fd.
 func FieldDecoder(oid uint32, bin bool) (res Decoder) {
-	if oid > pgtype.Int8rangeOID { // this is the max common oid pgtype knows about
+	decs, ok := lookupDecs(oid)
+	if !ok && oid > pgtype.Int8rangeOID { // this is the max common oid pgtype knows about
 		// we may have an enum so lets text decoders
-		oid = pgtype.TextOID
+		decs, ok = decmap[pgtype.TextOID]
 	}
-	decs, ok := decmap[oid]
 	if ok {
 		if bin {
 			res = decs.Binary
@@ -44,7 +45,7 @@ func FieldDecoder(oid uint32, bin bool) (res Decoder) {
 }
 
 func FieldDecoders(oid uint32) DecoderPair {
-	decs, ok := decmap[oid]
+	decs, ok := lookupDecs(oid)
 	if ok {
 		return decs
 	}
@@ -55,18 +56,29 @@ type DecoderPair struct{ Text, Binary Decoder }
 
 var decmap = map[uint32]DecoderPair{
 	pgtype.BoolOID:        {boolTextDec, boolBinDec},
+	pgtype.QCharOID:       {strDec, strDec},
+	pgtype.NameOID:        {strDec, strDec},
+	pgtype.OIDOID:         {intTextDec, oidBinDec},
 	pgtype.ByteaOID:       {rawTextDec, rawBinDec},
 	pgtype.Int2OID:        {intTextDec, int2BinDec},
 	pgtype.Int4OID:        {intTextDec, int4BinDec},
 	pgtype.Int8OID:        {intTextDec, int8BinDec},
 	pgtype.Float4OID:      {realTextDec, real4BinDec},
 	pgtype.Float8OID:      {realTextDec, real8BinDec},
+	pgtype.NumericOID:     {numericTextDec, numericBinDec},
 	pgtype.TextOID:        {strDec, strDec},
 	pgtype.VarcharOID:     {strDec, strDec},
+	pgtype.BPCharOID:      {strDec, strDec},
+	pgtype.InetOID:        {strDec, inetBinDec},
+	pgtype.CIDROID:        {strDec, inetBinDec},
+	pgtype.MacaddrOID:     {strDec, macBinDec},
+	774 /*Macaddr8OID*/ :  {strDec, macBinDec},
+	pgtype.BitOID:         {bitsTextDec, bitsBinDec},
+	pgtype.VarbitOID:      {bitsTextDec, bitsBinDec},
 	pgtype.UUIDOID:        {uuidTextDec, uuidBinDec},
-	pgtype.DateOID:        {dateTextDec, dateBinDec},
-	pgtype.TimestampOID:   {tsTextDec, tsBinDec},
-	pgtype.TimestamptzOID: {tstzTextDec, tstzBinDec},
+	pgtype.DateOID:        defaultTimeOpts.decs(pgtype.DateOID),
+	pgtype.TimestampOID:   defaultTimeOpts.decs(pgtype.TimestampOID),
+	pgtype.TimestamptzOID: defaultTimeOpts.decs(pgtype.TimestamptzOID),
 	pgtype.TimeOID:        {timeTextDec, timeBinDec},
 	pgtype.IntervalOID:    {intervalTextDec, intervalBinDec},
 	pgtype.JSONOID:        {jsonDec, jsonDec},
@@ -79,24 +91,54 @@ var decmap = map[uint32]DecoderPair{
 	pgtype.Int8ArrayOID:        arrayDecs(intTextDec, int8BinDec, typ.Int),
 	pgtype.Float4ArrayOID:      arrayDecs(realTextDec, real4BinDec, typ.Real),
 	pgtype.Float8ArrayOID:      arrayDecs(realTextDec, real8BinDec, typ.Real),
+	pgtype.NumericArrayOID:     arrayDecs(numericTextDec, numericBinDec, typ.Num),
 	pgtype.TextArrayOID:        arrayDecs(strDec, strDec, typ.Str),
 	pgtype.VarcharArrayOID:     arrayDecs(strDec, strDec, typ.Str),
+	pgtype.BPCharArrayOID:      arrayDecs(strDec, strDec, typ.Str),
+	1002 /*QCharArrayOID*/ :    arrayDecs(strDec, strDec, typ.Str),
+	1003 /*NameArrayOID*/ :     arrayDecs(strDec, strDec, typ.Str),
+	1028 /*OIDArrayOID*/ :      arrayDecs(intTextDec, oidBinDec, typ.Int),
+	pgtype.InetArrayOID:        arrayDecs(strDec, inetBinDec, typ.Str),
+	pgtype.CIDRArrayOID:        arrayDecs(strDec, inetBinDec, typ.Str),
+	1040 /*MacaddrArrayOID*/ :  arrayDecs(strDec, macBinDec, typ.Str),
+	775 /*Macaddr8ArrayOID*/ :  arrayDecs(strDec, macBinDec, typ.Str),
+	1561 /*BitArrayOID*/ :      arrayDecs(bitsTextDec, bitsBinDec, typ.Type{Kind: knd.Bits}),
+	1563 /*VarbitArrayOID*/ :   arrayDecs(bitsTextDec, bitsBinDec, typ.Type{Kind: knd.Bits}),
 	pgtype.UUIDArrayOID:        arrayDecs(uuidTextDec, uuidBinDec, typ.UUID),
-	pgtype.DateArrayOID:        arrayDecs(dateTextDec, dateBinDec, typ.Time),
-	pgtype.TimestampArrayOID:   arrayDecs(tsTextDec, tsBinDec, typ.Time),
-	pgtype.TimestamptzArrayOID: arrayDecs(tstzTextDec, tstzBinDec, typ.Time),
+	pgtype.DateArrayOID:        defaultTimeOpts.decs(pgtype.DateArrayOID),
+	pgtype.TimestampArrayOID:   defaultTimeOpts.decs(pgtype.TimestampArrayOID),
+	pgtype.TimestamptzArrayOID: defaultTimeOpts.decs(pgtype.TimestamptzArrayOID),
 	1183 /*TimeArrayOID*/ :     arrayDecs(timeTextDec, timeBinDec, typ.Span),
 	1187 /*IntervalArrayOID*/ : arrayDecs(intervalTextDec, intervalBinDec, typ.Span),
 	199 /* JSONArrayOID*/ :     arrayDecs(jsonDec, jsonDec, typ.Data),
 	pgtype.JSONBArrayOID:       arrayDecs(jsonDec, jsonbDec, typ.Data),
 }
 
+func init() {
+	// anonymous records have no attribute names
+	decmap[pgtype.RecordOID] = compositeDecs(nil, nil)
+	// range decoders are based on the element decoders
+	for oid, el := range rangeElems {
+		if _, ok := rangeElems[el]; !ok {
+			decmap[oid] = rangeDecs(decmap[el].Text, decmap[el].Binary)
+		}
+	}
+	for oid, el := range rangeElems {
+		if _, ok := rangeElems[el]; ok {
+			decmap[oid] = multirangeDecs(decmap[el])
+		}
+	}
+	for oid, el := range rangeArrays {
+		decmap[oid] = arrayDecs(decmap[el].Text, decmap[el].Binary, typ.Data)
+	}
+}
+
 func errDecoder(raw []byte) (lit.Val, error) {
 	return nil, fmt.Errorf("decoder not implemented")
 }
 
 // following is a simplified rewrite of the pgtype decoders for oids used by daql.
-// we handle null checks outside of the decoder, and use a local timezone for date and timestamp.
+// we handle null checks outside of the decoder, and use the time options location for date and timestamp.
 // we also reimplemented the text array parser to use less allocations.
 
 func boolTextDec(raw []byte) (lit.Val, error) {
@@ -146,6 +188,13 @@ func int8BinDec(raw []byte) (lit.Val, error) {
 	return lit.Int(binary.BigEndian.Uint64(raw)), nil
 }
 
+func oidBinDec(raw []byte) (lit.Val, error) {
+	if n := 4; len(raw) != n {
+		return nil, fmt.Errorf("invalid length for oid: %d", len(raw))
+	}
+	return lit.Int(binary.BigEndian.Uint32(raw)), nil
+}
+
 func realTextDec(raw []byte) (lit.Val, error) {
 	n, err := strconv.ParseFloat(string(raw), 64)
 	return lit.Real(n), err
@@ -165,10 +214,65 @@ func real8BinDec(raw []byte) (lit.Val, error) {
 	return lit.Real(math.Float64frombits(d)), nil
 }
 
+func numericTextDec(raw []byte) (lit.Val, error) {
+	// postgres always returns the canonical representation
+	return Numeric{lit.Str(raw)}, nil
+}
+func numericBinDec(raw []byte) (lit.Val, error) {
+	s, err := decodeNumericBin(raw)
+	if err != nil {
+		return nil, err
+	}
+	return Numeric{lit.Str(s)}, nil
+}
+
 func strDec(raw []byte) (lit.Val, error) {
 	return lit.Str(raw), nil
 }
 
+func inetBinDec(raw []byte) (lit.Val, error) {
+	// family, bits, is_cidr, addr length followed by the address
+	if len(raw) != 8 && len(raw) != 20 {
+		return nil, fmt.Errorf("invalid length for inet: %d", len(raw))
+	}
+	ip := net.IP(raw[4:])
+	bits, cidr := int(raw[1]), raw[2] == 1
+	if !cidr && bits == len(ip)*8 {
+		return lit.Str(ip.String()), nil
+	}
+	return lit.Str(fmt.Sprintf("%s/%d", ip, bits)), nil
+}
+
+func macBinDec(raw []byte) (lit.Val, error) {
+	if len(raw) != 6 && len(raw) != 8 {
+		return nil, fmt.Errorf("invalid length for macaddr: %d", len(raw))
+	}
+	return lit.Str(net.HardwareAddr(raw).String()), nil
+}
+
+func bitsTextDec(raw []byte) (lit.Val, error) {
+	return parseBitString(string(raw))
+}
+func bitsBinDec(raw []byte) (lit.Val, error) {
+	if len(raw) < 4 {
+		return nil, fmt.Errorf("invalid length for bit string: %d", len(raw))
+	}
+	n := int(int32(binary.BigEndian.Uint32(raw)))
+	if n > 64 {
+		return nil, fmt.Errorf("bit string length %d exceeds 64", n)
+	}
+	if len(raw) != 4+(n+7)/8 {
+		return nil, fmt.Errorf("invalid length for bit string: %d", len(raw))
+	}
+	var res uint64
+	for _, b := range raw[4:] {
+		res = res<<8 | uint64(b)
+	}
+	// drop the padding of the last byte
+	res >>= uint(len(raw[4:])*8 - n)
+	return Bits{lit.Int(res), n}, nil
+}
+
 func uuidTextDec(raw []byte) (lit.Val, error) {
 	u, err := cor.ParseUUID(string(raw))
 	return lit.UUID(u), err
@@ -182,59 +286,58 @@ func uuidBinDec(raw []byte) (lit.Val, error) {
 	return u, nil
 }
 
-func dateTextDec(raw []byte) (lit.Val, error) {
+func (o *TimeOpts) dateTextDec(raw []byte) (lit.Val, error) {
 	s := string(raw)
 	switch s {
 	case "infinity", "-infinity":
-		return lit.Time{}, nil
+		return o.inf(s[0] == '-')
 	}
-	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	t, err := time.ParseInLocation("2006-01-02", s, o.loc())
 	return lit.Time(t), err
 }
-func dateBinDec(raw []byte) (lit.Val, error) {
+func (o *TimeOpts) dateBinDec(raw []byte) (lit.Val, error) {
 	if n := 4; len(raw) != n {
 		return nil, fmt.Errorf("invalid length for date: %d", len(raw))
 	}
 	day := int32(binary.BigEndian.Uint32(raw))
 	switch day {
 	case math.MaxInt32, math.MinInt32:
-		return lit.Time{}, nil
+		return o.inf(day < 0)
 	}
-	t := time.Date(2000, 1, 1+int(day), 0, 0, 0, 0, time.Local)
+	t := time.Date(2000, 1, 1+int(day), 0, 0, 0, 0, o.loc())
 	return lit.Time(t), nil
 }
 
-func tsTextDec(raw []byte) (lit.Val, error) {
+func (o *TimeOpts) tsTextDec(raw []byte) (lit.Val, error) {
 	s := string(raw)
 	switch s {
 	case "infinity", "-infinity":
-		return lit.Time{}, nil
+		return o.inf(s[0] == '-')
 	}
 	t, err := time.Parse("2006-01-02 15:04:05.999999999", s)
-	return lit.Time(t.In(time.Local)), err
+	return lit.Time(o.stamp(t)), err
 }
-func tsBinDec(raw []byte) (lit.Val, error) {
+func (o *TimeOpts) tsBinDec(raw []byte) (lit.Val, error) {
 	if n := 8; len(raw) != n {
 		return nil, fmt.Errorf("invalid length for timestamp: %d", len(raw))
 	}
 	µs := int64(binary.BigEndian.Uint64(raw))
 	switch µs {
 	case math.MaxInt64, math.MinInt64:
-		return lit.Time{}, nil
+		return o.inf(µs < 0)
 	}
-	const sUnixToY2k = 946684800
 	t := time.Unix(
 		(µs/1_000_000)+sUnixToY2k,
 		(µs%1_000_000)*1_000,
 	)
-	return lit.Time(t), nil
+	return lit.Time(o.stamp(t.UTC())), nil
 }
 
-func tstzTextDec(raw []byte) (lit.Val, error) {
+func (o *TimeOpts) tstzTextDec(raw []byte) (lit.Val, error) {
 	s := string(raw)
 	switch s {
 	case "infinity", "-infinity":
-		return lit.Time{}, nil
+		return o.inf(s[0] == '-')
 	}
 	format := "2006-01-02 15:04:05.999999999Z07"
 	if len(s) < 14 {
@@ -246,25 +349,25 @@ func tstzTextDec(raw []byte) (lit.Val, error) {
 		format = "2006-01-02 15:04:05.999999999Z07:00"
 	}
 	t, err := time.Parse(format, s)
-	return lit.Time(t.In(time.Local)), err
+	return lit.Time(t.In(o.loc())), err
 }
 
 const sUnixToY2k = 946684800
 
-func tstzBinDec(raw []byte) (lit.Val, error) {
+func (o *TimeOpts) tstzBinDec(raw []byte) (lit.Val, error) {
 	if n := 8; len(raw) != n {
 		return nil, fmt.Errorf("invalid length for timestamptz: %d", len(raw))
 	}
 	µs := int64(binary.BigEndian.Uint64(raw))
 	switch µs {
 	case math.MaxInt64, math.MinInt64:
-		return lit.Time{}, nil
+		return o.inf(µs < 0)
 	}
 	t := time.Unix(
 		(µs/1_000_000)+sUnixToY2k,
 		(µs%1_000_000)*1_000,
 	)
-	return lit.Time(t), nil
+	return lit.Time(t.In(o.loc())), nil
 }
 
 func timeTextDec(raw []byte) (lit.Val, error) {
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	pgx "github.com/jackc/pgx/v4"
-	"github.com/jackc/pgx/v4/pgxpool"
 	"xelf.org/dapgx"
 	"xelf.org/daql/dom"
 	"xelf.org/xelf/cor"
@@ -14,14 +13,14 @@ import (
 	"xelf.org/xelf/lit"
 )
 
-func CreateProject(ctx context.Context, db *pgxpool.Pool, p *dom.Project) error {
+func CreateProject(ctx context.Context, db dapgx.DB, p *dom.Project) error {
 	return dapgx.WithTx(ctx, db, func(tx dapgx.PC) error {
 		err := dropProject(ctx, tx, p)
 		if err != nil {
 			return err
 		}
 		for _, s := range p.Schemas {
-			_, err = tx.Exec(ctx, "CREATE SCHEMA "+s.Name)
+			_, err = dapgx.TraceExec(ctx, tx, "CREATE SCHEMA "+s.Name)
 			if err != nil {
 				return err
 			}
@@ -36,7 +35,7 @@ func CreateProject(ctx context.Context, db *pgxpool.Pool, p *dom.Project) error
 	})
 }
 
-func DropProject(ctx context.Context, db *pgxpool.Pool, p *dom.Project) error {
+func DropProject(ctx context.Context, db dapgx.DB, p *dom.Project) error {
 	return dapgx.WithTx(ctx, db, func(tx dapgx.PC) error {
 		return dropProject(ctx, tx, p)
 	})
@@ -75,14 +74,14 @@ func createModel(ctx context.Context, tx dapgx.C, p *dom.Project, m *dom.Model,
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec(ctx, b.String())
+	_, err = dapgx.TraceExec(ctx, tx, b.String())
 	return err
 }
 
 func dropProject(ctx context.Context, tx dapgx.C, p *dom.Project) error {
 	for i := len(p.Schemas) - 1; i >= 0; i-- {
 		s := p.Schemas[i]
-		_, err := tx.Exec(ctx, "DROP SCHEMA IF EXISTS "+s.Name+" CASCADE")
+		_, err := dapgx.TraceExec(ctx, tx, "DROP SCHEMA IF EXISTS "+s.Name+" CASCADE")
 		if err != nil {
 			return err
 		}
@@ -90,7 +89,7 @@ func dropProject(ctx context.Context, tx dapgx.C, p *dom.Project) error {
 	return nil
 }
 
-func CopyFrom(ctx context.Context, db *pgxpool.Pool, reg *lit.Regs, s *dom.Schema, fix lit.Keyed) error {
+func CopyFrom(ctx context.Context, db dapgx.DB, reg *lit.Regs, s *dom.Schema, fix lit.Keyed) error {
 	return dapgx.WithTx(ctx, db, func(tx dapgx.PC) error {
 		for _, kv := range fix {
 			m := s.Model(kv.Key)
@@ -98,9 +97,12 @@ func CopyFrom(ctx context.Context, db *pgxpool.Pool, reg *lit.Regs, s *dom.Schem
 			for _, f := range m.Elems {
 				cols = append(cols, cor.Keyed(f.Name))
 			}
-			_, err := tx.CopyFrom(ctx, pgx.Identifier{m.Qual(), m.Key()}, cols, &litCopySrc{
+			tab := pgx.Identifier{m.Qual(), m.Key()}
+			ctx, end := dapgx.StartTrace(ctx, "COPY "+tab.Sanitize()+" FROM STDIN", nil)
+			n, err := tx.CopyFrom(ctx, tab, cols, &litCopySrc{
 				Vals: *kv.Val.(*lit.Vals), reg: reg, m: m,
 			})
+			end(n, err)
 			if err != nil {
 				return fmt.Errorf("copy from: %w", err)
 			}
@@ -12,6 +12,7 @@ import (
 	"xelf.org/xelf/bfr"
 	"xelf.org/xelf/cor"
 	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
 	"xelf.org/xelf/typ"
 )
 
@@ -153,7 +154,7 @@ func writeField(w *dapgx.Writer, p typ.Param, el *dom.Elem) error {
 	}
 	w.Fmt(checkIdent(key))
 	w.Byte(' ')
-	ts, err := dapgx.TypString(p.Type)
+	ts, err := dapgx.TypStringPrec(p.Type, extraInt(el.Extra, "prec"), extraInt(el.Extra, "scale"))
 	if err != nil {
 		return err
 	}
@@ -186,6 +187,10 @@ func writeField(w *dapgx.Writer, p typ.Param, el *dom.Elem) error {
 			w.Fmt(" default ''")
 		case "int8":
 			w.Fmt(" default 0")
+		default:
+			if strings.HasPrefix(ts, "numeric") {
+				w.Fmt(" default 0")
+			}
 		}
 	}
 	if el.Bits&dom.BitPK == 0 && el.Type.Ref != "" {
@@ -205,6 +210,18 @@ func writeField(w *dapgx.Writer, p typ.Param, el *dom.Elem) error {
 	return nil
 }
 
+func extraInt(d *lit.Dict, key string) int {
+	v, err := d.Key(key)
+	if err != nil || v == nil || v.Nil() {
+		return 0
+	}
+	n, err := lit.ToInt(v)
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
 func writeEmbed(w *dapgx.Writer, t typ.Type) error {
 	ref := t.Ref
 	ps := strings.Split(ref, ".")
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
+	"net"
 	"strconv"
 	"time"
 	"unicode/utf8"
@@ -19,10 +20,27 @@ import (
 	"xelf.org/xelf/lit"
 )
 
-func FieldEncoder(oid uint32, arg lit.Val) (encoder, error) {
+func FieldEncoder(oid uint32, arg lit.Val) (Encoder, error) {
 	if arg == nil || arg.Nil() {
 		return WrapNull{}, nil
 	}
+	if c := LookupCodec(oid); c != nil && c.Encoder != nil {
+		return c.Encoder(oid, arg)
+	}
+	if el, ok := rangeElems[oid]; ok {
+		if _, ok = rangeElems[el]; ok {
+			idxr, ok := lit.Unwrap(arg).(lit.Idxr)
+			if !ok {
+				return nil, fmt.Errorf("expect multirange list got %T", arg)
+			}
+			return WrapMultirange{idxr, el}, nil
+		}
+		keyr, ok := lit.Unwrap(arg).(lit.Keyr)
+		if !ok {
+			return nil, fmt.Errorf("expect range object got %T", arg)
+		}
+		return WrapRange{keyr, el}, nil
+	}
 	if oid > pgtype.Int8rangeOID { // this is the max common oid pgtype knows about
 		// we may have an enum so lets use the arg type as hint
 		k := arg.Type().Kind
@@ -55,8 +73,31 @@ func FieldEncoder(oid uint32, arg lit.Val) (encoder, error) {
 	case pgtype.Float8OID:
 		a, err := lit.ToReal(arg)
 		return WrapReal8(a), err
-	case pgtype.TextOID, pgtype.VarcharOID:
+	case pgtype.NumericOID:
+		a, err := ToNumeric(arg)
+		return WrapNumeric(a.Str), err
+	case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.QCharOID:
 		return WrapStr(arg.String()), nil
+	case pgtype.OIDOID:
+		a, err := lit.ToInt(arg)
+		return WrapOid(a), err
+	case pgtype.InetOID:
+		return WrapInet(arg.String()), nil
+	case pgtype.CIDROID:
+		return WrapCIDR(arg.String()), nil
+	case pgtype.MacaddrOID, 774 /*Macaddr8OID*/ :
+		return WrapMacaddr(arg.String()), nil
+	case pgtype.BitOID, pgtype.VarbitOID:
+		if k := arg.Type().Kind; k&knd.Char != 0 {
+			a := arg.String()
+			_, err := parseBitString(a)
+			return WrapBits(a), err
+		}
+		if b, ok := lit.Unwrap(arg).(Bits); ok {
+			return WrapBits(b.BitString()), nil
+		}
+		a, err := lit.ToInt(arg)
+		return WrapBits(formatBitString(uint64(a), 0)), err
 	case pgtype.UUIDOID:
 		a, err := lit.ToUUID(arg)
 		return WrapUUID(a), err
@@ -97,8 +138,30 @@ func FieldEncoder(oid uint32, arg lit.Val) (encoder, error) {
 			coid = pgtype.Float4OID
 		case pgtype.Float8ArrayOID:
 			coid = pgtype.Float8OID
+		case pgtype.NumericArrayOID:
+			coid = pgtype.NumericOID
 		case pgtype.TextArrayOID, pgtype.VarcharArrayOID:
 			coid = pgtype.TextOID
+		case pgtype.BPCharArrayOID:
+			coid = pgtype.BPCharOID
+		case 1002 /*QCharArrayOID*/ :
+			coid = pgtype.QCharOID
+		case 1003 /*NameArrayOID*/ :
+			coid = pgtype.NameOID
+		case 1028 /*OIDArrayOID*/ :
+			coid = pgtype.OIDOID
+		case pgtype.InetArrayOID:
+			coid = pgtype.InetOID
+		case pgtype.CIDRArrayOID:
+			coid = pgtype.CIDROID
+		case 1040 /*MacaddrArrayOID*/ :
+			coid = pgtype.MacaddrOID
+		case 775 /*Macaddr8ArrayOID*/ :
+			coid = 774 /*Macaddr8OID*/
+		case 1561 /*BitArrayOID*/ :
+			coid = pgtype.BitOID
+		case 1563 /*VarbitArrayOID*/ :
+			coid = pgtype.VarbitOID
 		case pgtype.UUIDArrayOID:
 			coid = pgtype.UUIDOID
 		case pgtype.DateArrayOID:
@@ -116,6 +179,10 @@ func FieldEncoder(oid uint32, arg lit.Val) (encoder, error) {
 		case pgtype.JSONBArrayOID:
 			coid = pgtype.JSONBOID
 		default:
+			if el, ok := rangeArrays[oid]; ok {
+				coid = int32(el)
+				break
+			}
 			return nil, fmt.Errorf("no array encoder for %T", arg)
 		}
 		return WrapIdxr{idxr, coid}, nil
@@ -127,7 +194,8 @@ func isSpace(b byte) bool { return b == ' ' || b > '\t' && b < '\r' }
 
 func quote(oid int32, raw []byte) []byte {
 	switch oid {
-	case pgtype.TextOID, pgtype.VarcharOID, pgtype.JSONOID, pgtype.JSONBOID:
+	case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.QCharOID,
+		pgtype.JSONOID, pgtype.JSONBOID:
 	default:
 		return raw
 	}
@@ -144,16 +212,17 @@ func quote(oid int32, raw []byte) []byte {
 			case '\\', '"':
 				res = append(res, '\\', raw[i])
 			default:
-				res = append(res, raw[i:n]...)
+				res = append(res, raw[i:i+n]...)
 			}
 			i += n
 		}
-		res = append(res, '"')
+		return append(res, '"')
 	}
 	return raw
 }
 
-type encoder interface {
+// Encoder is implemented by all field encoders used for query arguments.
+type Encoder interface {
 	pgtype.TextEncoder
 	pgtype.BinaryEncoder
 }
@@ -166,7 +235,13 @@ type (
 	WrapInt8      lit.Int
 	WrapReal4     lit.Real
 	WrapReal8     lit.Real
+	WrapNumeric   lit.Str
 	WrapStr       lit.Str
+	WrapOid       lit.Int
+	WrapInet      lit.Str
+	WrapCIDR      lit.Str
+	WrapMacaddr   lit.Str
+	WrapBits      lit.Str
 	WrapRaw       lit.Raw
 	WrapUUID      lit.UUID
 	WrapTime      lit.Time
@@ -230,6 +305,13 @@ func (w WrapReal8) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	return pgio.AppendUint64(b, math.Float64bits(float64(w))), nil
 }
 
+func (w WrapNumeric) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return append(b, string(w)...), nil
+}
+func (w WrapNumeric) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return appendNumericBin(b, string(w))
+}
+
 func (w WrapStr) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	return append(b, string(w)...), nil
 }
@@ -237,6 +319,85 @@ func (w WrapStr) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	return append(b, string(w)...), nil
 }
 
+func (w WrapOid) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return append(b, strconv.FormatUint(uint64(uint32(w)), 10)...), nil
+}
+func (w WrapOid) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return pgio.AppendUint32(b, uint32(w)), nil
+}
+
+func (w WrapInet) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return append(b, string(w)...), nil
+}
+func (w WrapInet) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return appendInetBin(b, string(w), false)
+}
+func (w WrapCIDR) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return append(b, string(w)...), nil
+}
+func (w WrapCIDR) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return appendInetBin(b, string(w), true)
+}
+
+func appendInetBin(b []byte, s string, cidr bool) ([]byte, error) {
+	ip, bits := net.ParseIP(s), -1
+	if ip == nil {
+		addr, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		ip = addr
+		if cidr {
+			ip = ipnet.IP
+		}
+		bits, _ = ipnet.Mask.Size()
+	}
+	family := byte(3) // PGSQL_AF_INET6
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, family = ip4, 2 // PGSQL_AF_INET
+	}
+	if bits < 0 {
+		bits = len(ip) * 8
+	}
+	var isCIDR byte
+	if cidr {
+		isCIDR = 1
+	}
+	b = append(b, family, byte(bits), isCIDR, byte(len(ip)))
+	return append(b, ip...), nil
+}
+
+func (w WrapMacaddr) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return append(b, string(w)...), nil
+}
+func (w WrapMacaddr) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	mac, err := net.ParseMAC(string(w))
+	if err != nil {
+		return nil, err
+	}
+	return append(b, mac...), nil
+}
+
+func (w WrapBits) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return append(b, string(w)...), nil
+}
+func (w WrapBits) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	b = pgio.AppendInt32(b, int32(len(w)))
+	var c byte
+	for i := 0; i < len(w); i++ {
+		if w[i] == '1' {
+			c |= 0x80 >> uint(i%8)
+		}
+		if i%8 == 7 {
+			b, c = append(b, c), 0
+		}
+	}
+	if len(w)%8 != 0 {
+		b = append(b, c)
+	}
+	return b, nil
+}
+
 func (w WrapRaw) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	return append(append(b, '\\', 'x'), hex.EncodeToString([]byte(w))...), nil
 }
@@ -252,15 +413,24 @@ func (w WrapUUID) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 }
 
 func (w WrapTime) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	if inf := infSign(time.Time(w)); inf != 0 {
+		return appendInfText(b, inf), nil
+	}
 	s := time.Time(w).UTC().Format("2006-01-02 15:04:05.999999Z07:00:00")
 	return append(b, s...), nil
 }
 func (w WrapTime) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	t := time.Time(w)
+	if inf := infSign(t); inf != 0 {
+		return appendInfInt64(b, inf), nil
+	}
 	µs := t.Unix()*1_000_000 + int64(t.Nanosecond())/1_000
 	return pgio.AppendInt64(b, µs-sUnixToY2k*1_000_000), nil
 }
 func (w WrapTimeDate) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	if inf := infSign(time.Time(w)); inf != 0 {
+		return appendInfText(b, inf), nil
+	}
 	s := time.Time(w).Format("2006-01-02")
 	return append(b, s...), nil
 }
@@ -269,20 +439,45 @@ var epochStamp = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
 
 func (w WrapTimeDate) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	t := time.Time(w)
+	if inf := infSign(t); inf > 0 {
+		return pgio.AppendInt32(b, math.MaxInt32), nil
+	} else if inf < 0 {
+		return pgio.AppendInt32(b, math.MinInt32), nil
+	}
 	s := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
 	days := int32((s - epochStamp) / 86400)
 	return pgio.AppendInt32(b, days), nil
 }
 func (w WrapTimestamp) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	if inf := infSign(time.Time(w)); inf != 0 {
+		return appendInfText(b, inf), nil
+	}
 	s := time.Time(w).UTC().Format("2006-01-02 15:04:05.999999")
 	return append(b, s...), nil
 }
 func (w WrapTimestamp) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	t := time.Time(w)
+	if inf := infSign(t); inf != 0 {
+		return appendInfInt64(b, inf), nil
+	}
 	µs := t.Unix()*1_000_000 + int64(t.Nanosecond())/1_000
 	return pgio.AppendInt64(b, µs-sUnixToY2k*1_000_000), nil
 }
 
+func appendInfText(b []byte, inf int) []byte {
+	if inf < 0 {
+		return append(b, "-infinity"...)
+	}
+	return append(b, "infinity"...)
+}
+
+func appendInfInt64(b []byte, inf int) []byte {
+	if inf < 0 {
+		return pgio.AppendInt64(b, math.MinInt64)
+	}
+	return pgio.AppendInt64(b, math.MaxInt64)
+}
+
 const days = 24 * time.Hour
 
 func (w WrapSpan) EncodeText(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
@@ -331,6 +526,16 @@ func (w WrapJSONB) EncodeBinary(_ *pgtype.ConnInfo, b []byte) ([]byte, error) {
 }
 
 func (w WrapIdxr) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return encodeTextArray(ci, b, w, FieldEncoder)
+}
+func (w WrapIdxr) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return encodeBinaryArray(ci, b, w, FieldEncoder)
+}
+
+// EncoderFunc returns an encoder for arg with the postgres type oid or an error.
+type EncoderFunc func(oid uint32, arg lit.Val) (Encoder, error)
+
+func encodeTextArray(ci *pgtype.ConnInfo, b []byte, w WrapIdxr, fenc EncoderFunc) ([]byte, error) {
 	if w.Idxr == nil {
 		return nil, nil
 	}
@@ -346,7 +551,7 @@ func (w WrapIdxr) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
 			b = append(b, "NULL"...)
 			return nil
 		}
-		enc, err := FieldEncoder(uint32(w.Oid), v)
+		enc, err := fenc(uint32(w.Oid), v)
 		if err != nil {
 			return err
 		}
@@ -359,7 +564,7 @@ func (w WrapIdxr) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
 	})
 	return append(b, '}'), err
 }
-func (w WrapIdxr) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+func encodeBinaryArray(ci *pgtype.ConnInfo, b []byte, w WrapIdxr, fenc EncoderFunc) ([]byte, error) {
 	if w.Idxr == nil {
 		return nil, nil
 	}
@@ -379,7 +584,7 @@ func (w WrapIdxr) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
 			b = pgio.AppendInt32(b, -1)
 			return nil
 		}
-		enc, err := FieldEncoder(uint32(w.Oid), v)
+		enc, err := fenc(uint32(w.Oid), v)
 		if err != nil {
 			return err
 		}
@@ -1,11 +1,13 @@
 package dapgx
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgtype"
 	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
 )
 
 type timeTest struct {
@@ -27,37 +29,336 @@ func TestTime(t *testing.T) {
 		same(pgtype.DateOID, time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)),
 	}
 	for _, test := range tests {
-		enc, err := FieldEncoder(test.oid, lit.Time(test.time))
-		if err != nil {
-			t.Errorf("no encoder %v", err)
-			continue
+		roundTrip(t, test.oid, lit.Time(test.time), lit.Time(test.want))
+	}
+}
+
+// roundTrip encodes val for oid in text and binary format, decodes both and reports an error
+// unless the results equal want. Custom literals like Bits are compared by their printed form.
+// It returns the text encoding or nil if any step failed.
+func roundTrip(t *testing.T, oid uint32, val, want lit.Val) []byte {
+	t.Helper()
+	enc, err := FieldEncoder(oid, val)
+	if err != nil {
+		t.Errorf("%d no encoder %v", oid, err)
+		return nil
+	}
+	txt, err := enc.EncodeText(nil, nil)
+	if err != nil {
+		t.Errorf("%d encode text %s: %v", oid, val, err)
+		return nil
+	}
+	bin, err := enc.EncodeBinary(nil, nil)
+	if err != nil {
+		t.Errorf("%d encode binary %s: %v", oid, val, err)
+		return nil
+	}
+	decs := FieldDecoders(oid)
+	tv, err := decs.Text(txt)
+	if err != nil {
+		t.Errorf("%d decode text %s: %v", oid, txt, err)
+		return nil
+	}
+	bv, err := decs.Binary(bin)
+	if err != nil {
+		t.Errorf("%d decode binary %s: %v", oid, val, err)
+		return nil
+	}
+	for _, v := range []lit.Val{tv, bv} {
+		if !lit.Equal(want, v) && want.String() != v.String() {
+			t.Errorf("%d round trip of %s want %s got %s", oid, val, want, v)
+			return nil
 		}
-		txt, err := enc.EncodeText(nil, nil)
+	}
+	return txt
+}
+
+func TestNumeric(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"0", "0"},
+		{"1", "1"},
+		{"-1.5", "-1.5"},
+		{"0.0001", "0.0001"},
+		{"10000", "10000"},
+		{"1.5e3", "1500"},
+		{"-2.50e-2", "-0.0250"},
+		{"12345678901234567890.000123", "12345678901234567890.000123"},
+		{"NaN", "NaN"},
+	}
+	for _, test := range tests {
+		n, err := ParseNumeric(test.raw)
 		if err != nil {
-			t.Errorf("encode text %v", err)
+			t.Errorf("parse %s: %v", test.raw, err)
 			continue
 		}
-		bin, err := enc.EncodeBinary(nil, nil)
-		if err != nil {
-			t.Errorf("encode binary %v", err)
-			continue
+		if got := n.String(); got != test.want {
+			t.Errorf("parse %s want %s got %s", test.raw, test.want, got)
 		}
-		decs := FieldDecoders(test.oid)
-		tv, err := decs.Text(txt)
-		if err != nil {
-			t.Errorf("decode text %v", err)
-			continue
+		if _, ok := n.Mut().Value().(Numeric); !ok {
+			t.Errorf("numeric %s lost its type as mutable", test.raw)
+		}
+		roundTrip(t, pgtype.NumericOID, n, n)
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		oid  uint32
+		val  lit.Val
+		text string
+	}{
+		{pgtype.Int8rangeOID, MakeRange(lit.Int(1), lit.Int(5), true, false, false), `["1","5")`},
+		{pgtype.Int8rangeOID, MakeRange(nil, lit.Int(5), false, true, false), `(,"5"]`},
+		{pgtype.Int8rangeOID, MakeRange(nil, nil, false, false, true), `empty`},
+	}
+	for _, test := range tests {
+		txt := roundTrip(t, test.oid, test.val, test.val)
+		if txt != nil && string(txt) != test.text {
+			t.Errorf("encode text want %s got %s", test.text, txt)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	ts := &Types{oids: map[uint32]*TypeInfo{
+		20001: {Oid: 20001, Name: "foo.kind", Kind: 'e', Cat: 'E', Labels: []string{"", "a", "b"}},
+		20002: {Oid: 20002, Name: "foo._kind", Kind: 'b', Cat: 'A', Elem: 20001},
+		20003: {Oid: 20003, Name: "foo.pos", Kind: 'd', Cat: 'N', Base: pgtype.Int4OID},
+		20004: {Oid: 20004, Name: "foo.node", Kind: 'c', Cat: 'C', Attrs: []Attr{
+			{Name: "id", Oid: pgtype.Int8OID},
+			{Name: "kind", Oid: 20001},
+		}},
+	}}
+	if got := ts.Resolve(20003); got != pgtype.Int4OID {
+		t.Errorf("resolve domain want int4 got %d", got)
+	}
+	v, err := ts.FieldDecoder(20003, false)([]byte("42"))
+	if err != nil || !lit.Equal(v, lit.Int(42)) {
+		t.Errorf("decode domain want 42 got %v %v", v, err)
+	}
+	v, err = ts.FieldDecoder(20002, false)([]byte("{a,b}"))
+	want := lit.NewList(typ.Str, lit.Str("a"), lit.Str("b"))
+	if err != nil || !lit.Equal(v, want) {
+		t.Errorf("decode enum array want %s got %v %v", want, v, err)
+	}
+	enc, err := ts.FieldEncoder(20002, want)
+	if err != nil {
+		t.Fatalf("encode enum array %v", err)
+	}
+	bin, err := enc.EncodeBinary(nil, nil)
+	if err != nil {
+		t.Fatalf("encode enum array binary %v", err)
+	}
+	v, err = ts.FieldDecoder(20002, true)(bin)
+	if err != nil || !lit.Equal(v, want) {
+		t.Errorf("decode enum array binary want %s got %v %v", want, v, err)
+	}
+	node := lit.MakeObj(lit.Keyed{{Key: "id", Val: lit.Int(7)}, {Key: "kind", Val: lit.Str("a")}})
+	enc, err = ts.FieldEncoder(20004, node)
+	if err != nil {
+		t.Fatalf("encode composite %v", err)
+	}
+	txt, err := enc.EncodeText(nil, nil)
+	if err != nil || string(txt) != "(7,a)" {
+		t.Errorf("encode composite text want (7,a) got %s %v", txt, err)
+	}
+	bin, err = enc.EncodeBinary(nil, nil)
+	if err != nil {
+		t.Fatalf("encode composite binary %v", err)
+	}
+	for i, raw := range [][]byte{txt, bin} {
+		v, err = ts.FieldDecoder(20004, i > 0)(raw)
+		if err != nil || !lit.Equal(v, node) {
+			t.Errorf("decode composite want %s got %v %v", node, v, err)
+		}
+	}
+}
+
+func TestBits(t *testing.T) {
+	v, err := FieldDecoders(pgtype.BitOID).Text([]byte("00000101"))
+	if err != nil {
+		t.Fatalf("decode bits %v", err)
+	}
+	b, ok := v.Mut().Value().(Bits)
+	if !ok || b.Width != 8 || b.BitString() != "00000101" {
+		t.Errorf("decode bits want width 8 got %#v", v)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		oid  int32
+		raw  string
+		want string
+	}{
+		{pgtype.Int8OID, "1", "1"},
+		{pgtype.TextOID, "a b", "a b"},
+		{pgtype.TextOID, "", `""`},
+		{pgtype.TextOID, "NULL", `"NULL"`},
+		{pgtype.BPCharOID, "ab ", `"ab "`},
+		{pgtype.NameOID, `a"b\`, `"a\"b\\"`},
+		{pgtype.VarcharOID, "{ä,ö}", `"{ä,ö}"`},
+	}
+	for _, test := range tests {
+		if got := string(quote(test.oid, []byte(test.raw))); got != test.want {
+			t.Errorf("quote %s want %s got %s", test.raw, test.want, got)
+		}
+	}
+}
+
+func TestMisc(t *testing.T) {
+	tests := []struct {
+		oid  uint32
+		val  lit.Val
+		want lit.Val // nil if the same as val
+		text string
+	}{
+		{pgtype.InetOID, lit.Str("192.168.0.1"), nil, "192.168.0.1"},
+		{pgtype.InetOID, lit.Str("192.168.0.1/24"), nil, "192.168.0.1/24"},
+		{pgtype.InetOID, lit.Str("::1"), nil, "::1"},
+		{pgtype.CIDROID, lit.Str("10.1.0.0/16"), nil, "10.1.0.0/16"},
+		{pgtype.MacaddrOID, lit.Str("08:00:2b:01:02:03"), nil, "08:00:2b:01:02:03"},
+		{pgtype.BPCharOID, lit.Str("ab "), nil, "ab "},
+		{pgtype.NameOID, lit.Str("pg_type"), nil, "pg_type"},
+		{pgtype.OIDOID, lit.Int(1700), nil, "1700"},
+		{pgtype.VarbitOID, lit.Int(5), Bits{Int: 5, Width: 3}, "101"},
+		{pgtype.VarbitOID, lit.Str("000100101"), Bits{Int: 37, Width: 9}, "000100101"},
+		{pgtype.BitOID, Bits{Int: 5, Width: 8}, nil, "00000101"},
+	}
+	for _, test := range tests {
+		want := test.want
+		if want == nil {
+			want = test.val
+		}
+		txt := roundTrip(t, test.oid, test.val, want)
+		if txt != nil && string(txt) != test.text {
+			t.Errorf("encode text want %s got %s", test.text, txt)
 		}
-		bv, err := decs.Binary(bin)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	RegisterTypeCodec("ltree", DecoderPair{Text: func(raw []byte) (lit.Val, error) {
+		return lit.Str(strings.ReplaceAll(string(raw), ".", "/")), nil
+	}}, func(oid uint32, arg lit.Val) (Encoder, error) {
+		return WrapStr(strings.ReplaceAll(arg.String(), "/", ".")), nil
+	})
+	defer UnregisterTypeCodec("ltree")
+	ts := &Types{oids: map[uint32]*TypeInfo{
+		30001: {Oid: 30001, Name: "public.ltree", Kind: 'b', Cat: 'U'},
+		30002: {Oid: 30002, Name: "public._ltree", Kind: 'b', Cat: 'A', Elem: 30001},
+	}}
+	if got := ts.Oid("ltree"); got != 30001 {
+		t.Errorf("resolve ltree want 30001 got %d", got)
+	}
+	dup := &Types{oids: map[uint32]*TypeInfo{
+		30003: {Oid: 30003, Name: "a.mood", Kind: 'e'},
+		30004: {Oid: 30004, Name: "b.mood", Kind: 'e'},
+	}}
+	if got := dup.Oid("mood"); got != 0 {
+		t.Errorf("resolve ambiguous mood want 0 got %d", got)
+	}
+	dup.path = []string{"pg_catalog", "b", "a"}
+	if got := dup.Oid("mood"); got != 30004 {
+		t.Errorf("resolve mood on search path want 30004 got %d", got)
+	}
+	enc, err := ts.FieldEncoder(30001, lit.Str("a/b"))
+	if err != nil {
+		t.Fatalf("encode ltree %v", err)
+	}
+	txt, err := enc.EncodeText(nil, nil)
+	if err != nil || string(txt) != "a.b" {
+		t.Errorf("encode ltree want a.b got %s %v", txt, err)
+	}
+	v, err := ts.FieldDecoder(30001, false)(txt)
+	if err != nil || !lit.Equal(v, lit.Str("a/b")) {
+		t.Errorf("decode ltree want a/b got %v %v", v, err)
+	}
+	_, err = ts.FieldDecoder(30001, true)(txt)
+	if err == nil {
+		t.Errorf("decode ltree binary want error")
+	}
+	v, err = ts.FieldDecoder(30002, false)([]byte("{a.b,c}"))
+	want := lit.NewList(typ.Data, lit.Str("a/b"), lit.Str("c"))
+	if err != nil || !lit.Equal(v, want) {
+		t.Errorf("decode ltree array want %s got %v %v", want, v, err)
+	}
+}
+
+func TestOidMatches(t *testing.T) {
+	tests := []struct {
+		oid  uint32
+		t    typ.Type
+		want bool
+	}{
+		{pgtype.Int8OID, typ.Int, true},
+		{pgtype.Int4OID, typ.Opt(typ.Real), true},
+		{pgtype.TextOID, typ.Int, false},
+		{pgtype.TextOID, typ.Str, true},
+		{pgtype.JSONBOID, typ.Int, true},
+		{pgtype.Int8ArrayOID, typ.ListOf(typ.Int), true},
+		{pgtype.Int8ArrayOID, typ.ListOf(typ.Str), false},
+		{pgtype.TimestamptzOID, typ.Span, false},
+		{90001, typ.Str, true},
+		{90001, typ.Int, false},
+	}
+	for _, test := range tests {
+		if got := oidMatches(nil, test.oid, test.t); got != test.want {
+			t.Errorf("oid %d with %s want %v got %v", test.oid, test.t, test.want, got)
+		}
+	}
+}
+
+func TestTimeOpts(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	o := &TimeOpts{Loc: cet, Inf: InfSentinel}
+	v, err := o.FieldDecoder(pgtype.TimestamptzOID, false)([]byte("2022-01-01 00:00:00+00"))
+	if err != nil {
+		t.Fatalf("decode timestamptz %v", err)
+	}
+	want := time.Date(2022, 1, 1, 1, 0, 0, 0, cet)
+	if got := time.Time(v.(lit.Time)); got != want {
+		t.Errorf("decode timestamptz want %s got %s", want, got)
+	}
+	v, err = o.FieldDecoder(pgtype.DateOID, false)([]byte("2022-01-01"))
+	if got := time.Time(v.(lit.Time)); err != nil || got.Location() != cet {
+		t.Errorf("decode date want location cet got %s %v", got, err)
+	}
+	for _, raw := range []string{"infinity", "-infinity"} {
+		v, err = o.FieldDecoder(pgtype.TimestampOID, false)([]byte(raw))
 		if err != nil {
-			t.Errorf("decode binary %v", err)
-			continue
+			t.Fatalf("decode %s %v", raw, err)
 		}
-		if !lit.Equal(lit.Time(test.want), tv) {
-			t.Errorf("text time does not match %s != %s", test.want, tv)
+		enc, err := FieldEncoder(pgtype.TimestampOID, v)
+		if err != nil {
+			t.Fatalf("no encoder %v", err)
 		}
-		if !lit.Equal(lit.Time(test.want), bv) {
-			t.Errorf("binary time does not match %s != %s", test.want, bv)
+		txt, err := enc.EncodeText(nil, nil)
+		if err != nil || string(txt) != raw {
+			t.Errorf("encode sentinel want %s got %s %v", raw, txt, err)
 		}
 	}
+	v, err = o.FieldDecoder(pgtype.TimestampOID, false)([]byte("2022-01-01 12:00:00"))
+	want = time.Date(2022, 1, 1, 12, 0, 0, 0, cet)
+	if got := time.Time(v.(lit.Time)); err != nil || got != want {
+		t.Errorf("decode timestamp want %s got %s %v", want, got, err)
+	}
+	enc, err := o.FieldEncoder(pgtype.TimestampOID, lit.Time(want.UTC()))
+	if err != nil {
+		t.Fatalf("no encoder %v", err)
+	}
+	if txt, err := enc.EncodeText(nil, nil); err != nil || string(txt) != "2022-01-01 12:00:00" {
+		t.Errorf("encode timestamp want wall time got %s %v", txt, err)
+	}
+	o.Inf = InfNull
+	if v, err = o.FieldDecoder(pgtype.DateOID, false)([]byte("infinity")); v == nil || !v.Nil() || err != nil {
+		t.Errorf("decode infinity want null got %v %v", v, err)
+	}
+	o.Inf = InfError
+	if _, err = o.FieldDecoder(pgtype.DateOID, false)([]byte("infinity")); err == nil {
+		t.Errorf("decode infinity want error")
+	}
 }
@@ -0,0 +1,158 @@
+package dapgx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"xelf.org/daql/dom"
+)
+
+// Error kinds of constraint errors that can be checked with errors.Is.
+var (
+	ErrUnique        = errors.New("unique violation")
+	ErrForeignKey    = errors.New("foreign key violation")
+	ErrNotNull       = errors.New("not null violation")
+	ErrCheck         = errors.New("check violation")
+	ErrExclusion     = errors.New("exclusion violation")
+	ErrSerialization = errors.New("serialization failure")
+)
+
+var errKinds = map[string]error{
+	"23505": ErrUnique,
+	"23503": ErrForeignKey,
+	"23502": ErrNotNull,
+	"23514": ErrCheck,
+	"23P01": ErrExclusion,
+	"40001": ErrSerialization,
+}
+
+// ConstraintError is a constraint violation or serialization failure translated back to the
+// daql model and field keys where possible.
+type ConstraintError struct {
+	Kind       error      // one of the error kinds like ErrUnique
+	Model      *dom.Model // the model of the table or nil
+	Keys       []string   // the model field keys of the affected columns, if the model is known
+	Columns    []string   // the affected column names, if known
+	Constraint string
+	Pg         *pgconn.PgError
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Kind == ErrSerialization {
+		return "serialization failure, the transaction can be retried"
+	}
+	var b strings.Builder
+	if e.Model != nil {
+		b.WriteString(e.Model.Qualified())
+	} else if e.Pg.TableName != "" {
+		fmt.Fprintf(&b, "%s.%s", e.Pg.SchemaName, e.Pg.TableName)
+	}
+	if len(e.Keys) > 0 {
+		fmt.Fprintf(&b, " %s", strings.Join(e.Keys, ", "))
+	} else if len(e.Columns) > 0 {
+		fmt.Fprintf(&b, " %s", strings.Join(e.Columns, ", "))
+	} else if e.Constraint != "" {
+		fmt.Fprintf(&b, " %s", e.Constraint)
+	}
+	switch e.Kind {
+	case ErrUnique:
+		b.WriteString(" already exists")
+	case ErrForeignKey:
+		if strings.Contains(e.Pg.Detail, "still referenced") {
+			b.WriteString(" is still referenced")
+		} else {
+			b.WriteString(" references a missing entry")
+		}
+	case ErrNotNull:
+		b.WriteString(" is required")
+	case ErrCheck:
+		b.WriteString(" is invalid")
+	case ErrExclusion:
+		b.WriteString(" conflicts with an existing entry")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (e *ConstraintError) Is(target error) bool { return target == e.Kind }
+func (e *ConstraintError) Unwrap() error        { return e.Pg }
+
+// TranslateErr returns a *ConstraintError for constraint violations and serialization failures in
+// err, with model and field keys resolved using the project pr. Other errors are returned as is.
+// Exec, Query and the other helpers return database errors unchanged, callers that want typed
+// errors must translate them.
+func TranslateErr(pr *dom.Project, err error) error {
+	var pe *pgconn.PgError
+	if !errors.As(err, &pe) {
+		return err
+	}
+	kind := errKinds[pe.Code]
+	if kind == nil {
+		return err
+	}
+	res := &ConstraintError{Kind: kind, Constraint: pe.ConstraintName, Pg: pe}
+	if pr != nil && pe.TableName != "" {
+		res.Model = pr.Model(fmt.Sprintf("%s.%s", pe.SchemaName, pe.TableName))
+	}
+	var cols []string
+	if pe.ColumnName != "" {
+		cols = []string{pe.ColumnName}
+	} else if cols = detailCols(pe.Detail); cols == nil {
+		cols = constraintCols(pe.ConstraintName, pe.TableName)
+	}
+	res.Columns = cols
+	if res.Model != nil {
+		res.Keys = fieldKeys(res.Model, cols)
+	}
+	return res
+}
+
+// fieldKeys returns the field keys of model m for the columns cols. Embedded fields are stored
+// as flat columns, their keys are found among the flattened params. Unknown columns are skipped.
+func fieldKeys(m *dom.Model, cols []string) []string {
+	ps, err := colParams(nil, m.Type())
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, c := range cols {
+		for _, p := range ps.sorted {
+			if k, _ := ColKey(p.Key, p.Type); k == c {
+				keys = append(keys, p.Key)
+				break
+			}
+		}
+	}
+	return keys
+}
+
+// detailCols returns the columns from error details like 'Key (a, b)=(1, 2) already exists.'
+func detailCols(detail string) []string {
+	if !strings.HasPrefix(detail, "Key (") {
+		return nil
+	}
+	end := strings.Index(detail, ")=(")
+	if end < 0 {
+		return nil
+	}
+	cols := strings.Split(detail[5:end], ", ")
+	for i, c := range cols {
+		cols[i] = strings.Trim(c, `"`)
+	}
+	return cols
+}
+
+// constraintCols returns the column from generated constraint names like table_col_check.
+func constraintCols(name, table string) []string {
+	if !strings.HasPrefix(name, table+"_") {
+		return nil
+	}
+	name = name[len(table)+1:]
+	for _, suf := range []string{"_check", "_key", "_fkey", "_excl", "_uniq"} {
+		if strings.HasSuffix(name, suf) {
+			return []string{name[:len(name)-len(suf)]}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package dapgx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+)
+
+func TestTranslateErr(t *testing.T) {
+	tests := []struct {
+		pe   *pgconn.PgError
+		kind error
+		want string
+	}{
+		{&pgconn.PgError{Code: "23505", SchemaName: "prod", TableName: "cat",
+			ConstraintName: "cat_name_key", Detail: "Key (name)=(foo) already exists."},
+			ErrUnique, "prod.cat name already exists"},
+		{&pgconn.PgError{Code: "23502", SchemaName: "prod", TableName: "cat", ColumnName: "name"},
+			ErrNotNull, "prod.cat name is required"},
+		{&pgconn.PgError{Code: "23514", SchemaName: "prod", TableName: "cat",
+			ConstraintName: "cat_size_check"},
+			ErrCheck, "prod.cat size is invalid"},
+		{&pgconn.PgError{Code: "23503", SchemaName: "prod", TableName: "prod",
+			ConstraintName: "prod_cat_fkey", Detail: `Key (cat)=(5) is not present in table "cat".`},
+			ErrForeignKey, "prod.prod cat references a missing entry"},
+		{&pgconn.PgError{Code: "40001"},
+			ErrSerialization, "serialization failure, the transaction can be retried"},
+	}
+	for _, test := range tests {
+		err := TranslateErr(nil, fmt.Errorf("failed insert: %w", test.pe))
+		if !errors.Is(err, test.kind) {
+			t.Errorf("want kind %v got %v", test.kind, err)
+			continue
+		}
+		if got := err.Error(); got != test.want {
+			t.Errorf("want %q got %q", test.want, got)
+		}
+	}
+	var ce *ConstraintError
+	err := TranslateErr(nil, tests[0].pe)
+	if !errors.As(err, &ce) || len(ce.Columns) != 1 || ce.Columns[0] != "name" || ce.Keys != nil {
+		t.Errorf("want column name without model keys got %#v", err)
+	}
+	err = fmt.Errorf("other")
+	if TranslateErr(nil, err) != err {
+		t.Errorf("want other errors unchanged")
+	}
+}
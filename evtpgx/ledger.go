@@ -51,15 +51,18 @@ func (l *ledger) Events(ctx context.Context, rev time.Time, tops ...string) ([]*
 
 func queryMaxRev(c dapgx.C) (time.Time, error) {
 	rev := time.Time{}
-	err := c.QueryRow(context.Background(),
-		"SELECT rev FROM evt.event ORDER BY rev DESC LIMIT 1").Scan(&rev)
-	if err != nil && err != pgx.ErrNoRows {
-		return rev, err
+	const sql = "SELECT rev FROM evt.event ORDER BY rev DESC LIMIT 1"
+	ctx, end := dapgx.StartTrace(context.Background(), sql, nil)
+	err := c.QueryRow(ctx, sql).Scan(&rev)
+	if err == pgx.ErrNoRows {
+		end(0, nil)
+		return rev, nil
 	}
-	return rev, nil
+	end(1, err)
+	return rev, err
 }
 func (l *ledger) queryEvents(ctx context.Context, c dapgx.C, whr string, args ...interface{}) (res []*evt.Event, _ error) {
-	rows, err := c.Query(ctx, fmt.Sprintf("SELECT id, rev, top, key, cmd, arg "+
+	rows, err := dapgx.TraceQuery(ctx, c, fmt.Sprintf("SELECT id, rev, top, key, cmd, arg "+
 		"FROM evt.event %s ORDER BY id", whr), args...)
 	if err != nil {
 		return nil, err
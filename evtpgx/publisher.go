@@ -3,7 +3,6 @@ package evtpgx
 import (
 	"context"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
@@ -87,21 +86,15 @@ func (p *Publisher) Publish(t evt.Trans) (time.Time, []*evt.Event, error) {
 				// TODO check for conflict
 			}
 		}
+		// failed statements are reported to the dapgx tracer
 		err = p.apply(ctx, &p.publisher, c, evs)
 		if err != nil {
-			log.Printf("apply failed %v", err)
-			return err
+			return fmt.Errorf("apply events: %w", err)
 		}
-		// insert audit
-		err = p.insertAudit(ctx, c, rev, t.Audit)
-		if err != nil {
-			log.Printf("insert audit failed %v", err)
-			return err
-		}
-		return nil
+		return p.insertAudit(ctx, c, rev, t.Audit)
 	})
 	if err != nil {
-		return p.rev, nil, err
+		return p.rev, nil, dapgx.TranslateErr(p.Project(), err)
 	}
 	p.rev = rev
 	return p.rev, evs, nil
@@ -133,19 +126,20 @@ func scanOne(rows pgx.Rows, arg ...interface{}) error {
 }
 
 func insertEvents(ctx context.Context, p *publisher, c dapgx.PC, evs []*evt.Event) error {
+	var b dapgx.Batch
 	for _, ev := range evs {
-		rows, err := dapgx.Query(ctx, c, `INSERT INTO evt.event
+		ev := ev
+		b.QueueRows(`INSERT INTO evt.event
 			(rev, top, key, cmd, arg) VALUES
 			($1, $2, $3, $4, $5) returning id`, []lit.Val{
 			lit.Time(ev.Rev), lit.Str(ev.Top), lit.Str(ev.Key), lit.Str(ev.Cmd), ev.Arg,
+		}, func(rows pgx.Rows) error {
+			return scanOne(rows, &ev.ID)
 		})
-		if err != nil {
-			return fmt.Errorf("insert events: %w", err)
-		}
-		err = scanOne(rows, &ev.ID)
-		if err != nil {
-			return fmt.Errorf("insert events: %w", err)
-		}
+	}
+	_, err := b.Send(ctx, c)
+	if err != nil {
+		return fmt.Errorf("insert events: %w", err)
 	}
 	return nil
 }
@@ -168,7 +162,7 @@ func applyEvent(ctx context.Context, p *publisher, c dapgx.PC, ev *evt.Event) er
 	switch ev.Cmd {
 	case evt.CmdDel:
 		stmt := fmt.Sprintf("DELETE FROM %s WHERE id = $1", m.Qualified())
-		_, err := c.Exec(ctx, stmt, ev.Key)
+		_, err := dapgx.TraceExec(ctx, c, stmt, ev.Key)
 		if err != nil {
 			return err
 		}
@@ -187,7 +181,7 @@ func applyEvent(ctx context.Context, p *publisher, c dapgx.PC, ev *evt.Event) er
 		}
 		err = dapgx.Exec(ctx, c, qry, args)
 		if err != nil {
-			return fmt.Errorf("failed insert err: %v\nfor query: %s\nargs: %s", err, qry, ev.Arg)
+			return fmt.Errorf("failed insert err: %w\nfor query: %s\nargs: %s", err, qry, ev.Arg)
 		}
 	case evt.CmdMod:
 		qry, args, err := p.updateObj(m, ev)
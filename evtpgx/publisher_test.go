@@ -1,9 +1,11 @@
 package evtpgx
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"xelf.org/dapgx"
 	"xelf.org/daql/evt"
 	"xelf.org/xelf/lit"
 )
@@ -68,3 +70,29 @@ func TestPublisher(t *testing.T) {
 		t.Errorf("want 1 persons got %d %v", persn, err)
 	}
 }
+
+func TestPublisherUnique(t *testing.T) {
+	reg, pr, db := testSetup(t)
+	defer db.Close()
+	l, err := NewStatefulPublisher(db, pr, reg)
+	if err != nil {
+		t.Fatalf("create publisher %v", err)
+	}
+	group := evt.Trans{Acts: []evt.Action{
+		{Sig: evt.Sig{"person.group", "1"}, Cmd: evt.CmdNew, Arg: &lit.Dict{Keyed: []lit.KeyVal{
+			{Key: "name", Val: lit.Str("Test")},
+		}}},
+	}}
+	_, _, err = l.Publish(group)
+	if err != nil {
+		t.Fatalf("first %v", err)
+	}
+	_, _, err = l.Publish(group)
+	if !errors.Is(err, dapgx.ErrUnique) {
+		t.Fatalf("duplicate want unique violation got %v", err)
+	}
+	groupn, err := queryCount(db, "person.group")
+	if err != nil || groupn != 1 {
+		t.Errorf("want 1 groups got %d %v", groupn, err)
+	}
+}
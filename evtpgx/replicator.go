@@ -134,7 +134,7 @@ func (r *Replicator) PublishLocal(data evt.Trans) (lrev time.Time, evs []*evt.Ev
 		return nil
 	})
 	if err != nil {
-		return r.lrev, nil, err
+		return r.lrev, nil, dapgx.TranslateErr(r.Project(), err)
 	}
 	r.lrev = t.Rev
 	r.local = append(r.local, t)
@@ -163,7 +163,7 @@ func reviseActions(c dapgx.C, t *evt.Trans) ([]*evt.Event, error) {
 }
 
 func (p *publisher) queryLocal(ctx context.Context, c dapgx.C) ([]*evt.Trans, error) {
-	rows, err := c.Query(ctx, `SELECT id, base, rev, created, arrived, usr, extra, acts
+	rows, err := dapgx.TraceQuery(ctx, c, `SELECT id, base, rev, created, arrived, usr, extra, acts
 		FROM evt.trans ORDER BY id`)
 	if err != nil {
 		return nil, err
@@ -193,7 +193,7 @@ func deleteLocal(ctx context.Context, c dapgx.C, drop []int64) error {
 		fmt.Fprintf(&str, "%d", id)
 	}
 	str.WriteByte(')')
-	_, err := c.Exec(ctx, str.String())
+	_, err := dapgx.TraceExec(ctx, c, str.String())
 	return err
 }
 func (s *Replicator) checkLocal(evs []*evt.Event) (drop []int64) {
@@ -0,0 +1,149 @@
+package dapgx
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/cor"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+)
+
+// ExportFormat selects the output format of exported rows.
+type ExportFormat uint8
+
+const (
+	ExportXelf ExportFormat = iota // one xelf dict literal per line
+	ExportJSON                     // one json object per line
+	ExportCSV                      // csv with a header line of column names
+)
+
+// Export queries sql with args using pc and writes all result rows to w in format f. The rows are
+// streamed from the server like with a cursor and are decoded with the same field decoders as
+// the Scanner, so that times, spans and uuids are formatted like scanned literals.
+// It returns the number of exported rows.
+//
+// We do not use COPY TO STDOUT even for csv, because it cannot bind query parameters and
+// renders values in the postgres text format instead of the xelf formatting of the scanner.
+func Export(ctx context.Context, pc PC, w io.Writer, f ExportFormat, sql string, args []lit.Val) (int64, error) {
+	rows, err := Query(ctx, pc, sql, args)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	return ExportRows(w, f, rows)
+}
+
+// ExportRows writes all rows to w in format f and returns the number of written rows.
+func ExportRows(w io.Writer, f ExportFormat, rows pgx.Rows) (n int64, err error) {
+	fds := rows.FieldDescriptions()
+	ts, to := RowsTypes(rows), RowsTimeOpts(rows)
+	keys := make([]string, len(fds))
+	decs := make([]Decoder, len(fds))
+	for i, fd := range fds {
+		keys[i] = string(fd.Name)
+		decs[i] = fieldDecoder(ts, to, fd.DataTypeOID, fd.Format)
+	}
+	bw := bufio.NewWriter(w)
+	var write func([]lit.Val) error
+	flush := bw.Flush
+	switch f {
+	case ExportXelf, ExportJSON:
+		p := &bfr.P{Writer: bw, JSON: f == ExportJSON}
+		write = func(vals []lit.Val) error { return writeRecord(p, keys, vals) }
+	case ExportCSV:
+		// the csv writer has its own buffer that we flush once at the end
+		cw := csv.NewWriter(w)
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+		if err = cw.Write(keys); err != nil {
+			return 0, err
+		}
+		rec := make([]string, len(keys))
+		write = func(vals []lit.Val) error {
+			for i, v := range vals {
+				if rec[i], err = csvText(v); err != nil {
+					return fmt.Errorf("export column %s: %w", keys[i], err)
+				}
+			}
+			return cw.Write(rec)
+		}
+	default:
+		return 0, fmt.Errorf("unknown export format %d", f)
+	}
+	vals := make([]lit.Val, len(fds))
+	for rows.Next() {
+		for i, raw := range rows.RawValues() {
+			vals[i] = nil
+			if raw != nil {
+				if vals[i], err = decs[i](raw); err != nil {
+					return n, fmt.Errorf("export column %s: %w", keys[i], err)
+				}
+			}
+		}
+		if err = write(vals); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err = rows.Err(); err != nil {
+		return n, err
+	}
+	return n, flush()
+}
+
+// writeRecord writes one line with a xelf dict or json object of keys and vals to p.
+func writeRecord(p *bfr.P, keys []string, vals []lit.Val) error {
+	p.Byte('{')
+	for i, key := range keys {
+		if i > 0 {
+			if p.JSON {
+				p.Byte(',')
+			} else {
+				p.Byte(' ')
+			}
+		}
+		if p.JSON || !cor.IsKey(key) {
+			raw, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			p.Write(raw)
+		} else {
+			p.WriteString(key)
+		}
+		p.Byte(':')
+		if v := vals[i]; v == nil || v.Nil() {
+			p.WriteString("null")
+		} else if err := v.Print(p); err != nil {
+			return err
+		}
+	}
+	p.Byte('}')
+	return p.Byte('\n')
+}
+
+// csvText returns the plain text of v for csv fields. Null is an empty field, character values
+// are not quoted and containers are written as json.
+func csvText(v lit.Val) (string, error) {
+	if v == nil || v.Nil() {
+		return "", nil
+	}
+	switch k := v.Type().Kind; {
+	case k&knd.Char != 0:
+		s, err := lit.ToStr(v)
+		return string(s), err
+	case k&(knd.Keyr|knd.Idxr) != 0:
+		raw, err := v.MarshalJSON()
+		return string(raw), err
+	}
+	return v.String(), nil
+}
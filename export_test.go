@@ -0,0 +1,43 @@
+package dapgx
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	db := testDB(t, "")
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire %v", err)
+	}
+	defer conn.Release()
+	const sql = `SELECT * FROM (VALUES
+		(1::int8, 'x'::text, ARRAY[1,2]::int8[], ARRAY['a, b',NULL]::text[]),
+		(2, NULL, NULL, '{}')
+	) AS t(id, name, ids, tags) ORDER BY id`
+	tests := []struct {
+		f    ExportFormat
+		want string
+	}{
+		{ExportCSV, "id,name,ids,tags\n" +
+			"1,x,\"[1,2]\",\"[\"\"a, b\"\",null]\"\n" +
+			"2,,,[]\n"},
+		{ExportJSON, `{"id":1,"name":"x","ids":[1,2],"tags":["a, b",null]}` + "\n" +
+			`{"id":2,"name":null,"ids":null,"tags":[]}` + "\n"},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		n, err := Export(ctx, conn.Conn(), &b, test.f, sql, nil)
+		if err != nil {
+			t.Errorf("export %d: %v", test.f, err)
+			continue
+		}
+		if got := b.String(); n != 2 || got != test.want {
+			t.Errorf("export %d want 2 rows\n%s\ngot %d rows\n%s", test.f, test.want, n, got)
+		}
+	}
+}
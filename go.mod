@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/jackc/pgconn v1.13.0
 	github.com/jackc/pgio v1.0.0
+	github.com/jackc/pgproto3/v2 v2.3.1
 	github.com/jackc/pgtype v1.13.0
 	github.com/jackc/pgx/v4 v4.17.2
 	golang.org/x/crypto v0.4.0 // indirect
@@ -0,0 +1,104 @@
+package dapgx
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+)
+
+// ErrStop can be returned from ScanEach callbacks to stop the iteration without an error.
+var ErrStop = errors.New("stop iteration")
+
+// ScanEach scans rows one at a time into new elements and calls fn for each of them. Unlike
+// ScanMany it does not collect the results. The iteration stops at the first error, when fn
+// returns ErrStop or when ctx is canceled. The rows are always closed.
+func ScanEach(ctx context.Context, scal bool, rows pgx.Rows, newEl func() lit.Mut, fn func(lit.Val) error) error {
+	it := NewIter(ctx, scal, rows)
+	defer it.Close()
+	for it.Next() {
+		el := newEl()
+		err := it.Scan(el)
+		if err == nil {
+			err = fn(el)
+		}
+		if err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// ScanChan starts scanning rows in a new goroutine and sends the new elements on the returned
+// value channel. The error channel receives the final result after the value channel is closed.
+// Cancel ctx to stop early, the rows must not be used by other goroutines until then.
+func ScanChan(ctx context.Context, scal bool, rows pgx.Rows, newEl func() lit.Mut) (<-chan lit.Val, <-chan error) {
+	vc, ec := make(chan lit.Val), make(chan error, 1)
+	go func() {
+		err := ScanEach(ctx, scal, rows, newEl, func(v lit.Val) error {
+			select {
+			case vc <- v:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(vc)
+		ec <- err
+		close(ec)
+	}()
+	return vc, ec
+}
+
+// Iter is a row iterator that decodes one row at a time and reuses the column decoders of the
+// scanner, that is created for the first row.
+type Iter struct {
+	ctx  context.Context
+	rows pgx.Rows
+	scal bool
+	s    *Scanner
+	err  error
+}
+
+// NewIter returns a new iterator over rows. It stops early if ctx is canceled.
+func NewIter(ctx context.Context, scal bool, rows pgx.Rows) *Iter {
+	return &Iter{ctx: ctx, rows: rows, scal: scal}
+}
+
+// Next advances the iterator to the next row and returns whether there is one.
+func (it *Iter) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		it.rows.Close()
+		return false
+	}
+	return it.rows.Next()
+}
+
+// Scan decodes the current row into mut.
+func (it *Iter) Scan(mut lit.Mut) (err error) {
+	if it.s == nil {
+		it.s, err = NewScanner(it.scal, it.rows)
+		if err != nil {
+			return err
+		}
+	}
+	return it.s.Scan(mut)
+}
+
+// Err returns the error that stopped the iteration or nil.
+func (it *Iter) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+// Close closes the rows. It is safe to call Close multiple times.
+func (it *Iter) Close() { it.rows.Close() }
@@ -0,0 +1,59 @@
+package dapgx
+
+import (
+	"context"
+	"testing"
+
+	"xelf.org/xelf/lit"
+)
+
+func TestScanEachStop(t *testing.T) {
+	db := testDB(t, "")
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire %v", err)
+	}
+	defer conn.Release()
+	const sql = "select generate_series(1, 1000)::int8"
+	newEl := func() lit.Mut { return new(lit.Int) }
+	rows, err := Query(ctx, conn.Conn(), sql, nil)
+	if err != nil {
+		t.Fatalf("query %v", err)
+	}
+	var got []int64
+	err = ScanEach(ctx, true, rows, newEl, func(v lit.Val) error {
+		got = append(got, int64(*v.(*lit.Int)))
+		if len(got) == 3 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("want stop without error got %v", err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Errorf("want 1 2 3 got %v", got)
+	}
+	// the rows are closed and the connection can be used again
+	rows, err = Query(ctx, conn.Conn(), sql, nil)
+	if err != nil {
+		t.Fatalf("query after stop %v", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	vc, ec := ScanChan(cctx, true, rows, newEl)
+	n := 0
+	for range vc {
+		if n++; n == 2 {
+			cancel()
+		}
+	}
+	if err = <-ec; err != context.Canceled {
+		t.Errorf("want canceled got %v after %d values", err, n)
+	}
+	if n > 3 {
+		t.Errorf("want at most 3 values after cancel got %d", n)
+	}
+}
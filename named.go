@@ -0,0 +1,201 @@
+package dapgx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+)
+
+// QueryNamed is like Query but binds named parameters like :name or $name from arg.
+func QueryNamed(ctx context.Context, pc PC, sql string, arg lit.Keyr) (pgx.Rows, error) {
+	sql, args, err := BindNamed(sql, arg)
+	if err != nil {
+		return nil, err
+	}
+	return Query(ctx, pc, sql, args)
+}
+
+// ExecNamed is like Exec but binds named parameters like :name or $name from arg.
+func ExecNamed(ctx context.Context, pc PC, sql string, arg lit.Keyr) error {
+	sql, args, err := BindNamed(sql, arg)
+	if err != nil {
+		return err
+	}
+	return Exec(ctx, pc, sql, args)
+}
+
+// BindNamed returns sql with named parameters replaced by positional parameters and the
+// arguments from arg in parameter order or an error. All keys of arg must be used.
+func BindNamed(sql string, arg lit.Keyr) (string, []lit.Val, error) {
+	res, names, err := ParseNamed(sql)
+	if err != nil {
+		return "", nil, err
+	}
+	keys := make(map[string]bool)
+	if arg != nil {
+		for _, k := range arg.Keys() {
+			keys[k] = false
+		}
+	}
+	var unknown []string
+	args := make([]lit.Val, 0, len(names))
+	for _, n := range names {
+		if _, ok := keys[n]; !ok {
+			unknown = append(unknown, n)
+			continue
+		}
+		keys[n] = true
+		v, err := arg.Key(n)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, v)
+	}
+	if len(unknown) > 0 {
+		return "", nil, fmt.Errorf("unknown named params: %s", strings.Join(unknown, ", "))
+	}
+	var unused []string
+	for k, used := range keys {
+		if !used {
+			unused = append(unused, k)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", nil, fmt.Errorf("unused named params: %s", strings.Join(unused, ", "))
+	}
+	return res, args, nil
+}
+
+// ParseNamed returns sql with named parameters replaced by positional parameters and the
+// parameter names in positional order or an error. Named parameters start with a colon or dollar
+// sign followed by an identifier. Repeated names use the same position. String literals, quoted
+// identifiers, dollar-quoted strings, comments and type casts are left as is. A colon directly
+// after an identifier, number or closing bracket is an array slice like arr[1:n] or arr[lo:hi].
+// Slices without lower bound like arr[:n] are read as index param, use arr[1:n] instead.
+func ParseNamed(sql string) (string, []string, error) {
+	var b strings.Builder
+	var names []string
+	pos := make(map[string]int)
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		var end int
+		switch {
+		case c == '\'' || c == '"':
+			end = skipQuoted(sql, i, c, c == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e'))
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end = strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end = skipComment(sql, i)
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			end = i + 2
+		case c == ':' && i > 0 && isSliceBound(sql[i-1]):
+			end = i + 1
+		case c == ':' || c == '$':
+			n := identEnd(sql, i+1)
+			if c == '$' && n < len(sql) && sql[n] == '$' {
+				// dollar-quoted string
+				tag := sql[i : n+1]
+				end = strings.Index(sql[n+1:], tag)
+				if end < 0 {
+					return "", nil, fmt.Errorf("unterminated dollar-quoted string at %d", i)
+				}
+				end += n + 1 + len(tag)
+				break
+			}
+			if n == i+1 {
+				if c == '$' && n < len(sql) && sql[n] >= '0' && sql[n] <= '9' {
+					return "", nil, fmt.Errorf("positional param at %d mixed with named params", i)
+				}
+				end = n
+				break
+			}
+			name := sql[i+1 : n]
+			p, ok := pos[name]
+			if !ok {
+				names = append(names, name)
+				p = len(names)
+				pos[name] = p
+			}
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(p))
+			i = n
+			continue
+		default:
+			end = i + 1
+		}
+		if end < 0 {
+			return "", nil, fmt.Errorf("unterminated quote or comment at %d", i)
+		}
+		b.WriteString(sql[i:end])
+		i = end
+	}
+	return b.String(), names, nil
+}
+
+// identEnd returns the end of an identifier starting at i or i if there is none.
+func identEnd(s string, i int) int {
+	for n := i; n < len(s); n++ {
+		c := s[n]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || n > i && c >= '0' && c <= '9' {
+			continue
+		}
+		return n
+	}
+	return len(s)
+}
+
+// isSliceBound returns whether c can end the lower bound of an array slice.
+func isSliceBound(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == ')' || c == ']'
+}
+
+// skipQuoted returns the end of the quoted text starting at i or -1. Quotes are escaped by
+// doubling them and, in escape strings, with a backslash.
+func skipQuoted(s string, i int, q byte, esc bool) int {
+	for n := i + 1; n < len(s); n++ {
+		switch s[n] {
+		case '\\':
+			if esc {
+				n++
+			}
+		case q:
+			if n+1 < len(s) && s[n+1] == q {
+				n++
+				continue
+			}
+			return n + 1
+		}
+	}
+	return -1
+}
+
+// skipComment returns the end of the possibly nested block comment starting at i or -1.
+func skipComment(s string, i int) int {
+	depth := 0
+	for n := i; n+1 < len(s); n++ {
+		switch {
+		case s[n] == '/' && s[n+1] == '*':
+			depth++
+			n++
+		case s[n] == '*' && s[n+1] == '/':
+			depth--
+			n++
+			if depth == 0 {
+				return n + 1
+			}
+		}
+	}
+	return -1
+}
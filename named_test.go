@@ -0,0 +1,44 @@
+package dapgx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNamed(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  string
+		names []string
+	}{
+		{"SELECT 1", "SELECT 1", nil},
+		{"SELECT * FROM usr WHERE id = :id", "SELECT * FROM usr WHERE id = $1", []string{"id"}},
+		{"SELECT $a::int, :b, $a", "SELECT $1::int, $2, $1", []string{"a", "b"}},
+		{"SELECT ':no', E'\\':no', \"$no\" -- :no\n, :yes", "SELECT ':no', E'\\':no', \"$no\" -- :no\n, $1", []string{"yes"}},
+		{"SELECT /* :no /* :no */ */ $$ :no $$, $t$ $no $t$, :yes",
+			"SELECT /* :no /* :no */ */ $$ :no $$, $t$ $no $t$, $1", []string{"yes"}},
+		{"SELECT arr[1:2] FROM x WHERE y = 'it''s :no' AND z = :z",
+			"SELECT arr[1:2] FROM x WHERE y = 'it''s :no' AND z = $1", []string{"z"}},
+		{"SELECT arr[1:n], arr[lo:hi], arr[f(x):g(y)], arr[:i], arr[1::int:j], ARRAY[:a, :b]",
+			"SELECT arr[1:n], arr[lo:hi], arr[f(x):g(y)], arr[$1], arr[1::int:j], ARRAY[$2, $3]",
+			[]string{"i", "a", "b"}},
+	}
+	for _, test := range tests {
+		got, names, err := ParseNamed(test.raw)
+		if err != nil {
+			t.Errorf("parse %s: %v", test.raw, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parse %s\nwant %s\n got %s", test.raw, test.want, got)
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("parse %s want names %v got %v", test.raw, test.names, names)
+		}
+	}
+	for _, raw := range []string{"SELECT $1, :a", "SELECT 'open", "SELECT /* open", "SELECT $x$ open"} {
+		if _, _, err := ParseNamed(raw); err == nil {
+			t.Errorf("parse %s want error", raw)
+		}
+	}
+}
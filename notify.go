@@ -0,0 +1,146 @@
+package dapgx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"xelf.org/xelf/lit"
+)
+
+// Notification is a postgres notification received by a listener.
+type Notification struct {
+	Channel string
+	PID     uint32 // the process id of the notifying backend
+	Payload string
+}
+
+// Lit parses the payload as xelf or json literal. An empty payload returns nil.
+func (n *Notification) Lit() (lit.Val, error) {
+	if n.Payload == "" {
+		return nil, nil
+	}
+	return lit.Read(strings.NewReader(n.Payload), "notification")
+}
+
+// ListenRetry is the backoff used by listeners to reconnect after the connection was lost.
+var ListenRetry = Retry{Backoff: 100 * time.Millisecond, Limit: 10 * time.Second}
+
+// Listener receives notifications on a dedicated connection, that is taken from the pool and not
+// returned. Lost connections are replaced and listen to the same channels again. Notifications
+// sent while reconnecting are lost.
+type Listener struct {
+	// C receives the notifications and is closed when the listener stops.
+	C <-chan *Notification
+
+	mu  sync.Mutex
+	err error
+}
+
+// Listen starts listening to channels using a dedicated connection from pool and returns the
+// listener or the error of the first connection attempt. The listener stops when ctx is canceled.
+func Listen(ctx context.Context, pool *pgxpool.Pool, channels ...string) (*Listener, error) {
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("listen without channels")
+	}
+	conn, err := listenConn(ctx, pool, channels)
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan *Notification)
+	l := &Listener{C: c}
+	go l.run(ctx, pool, channels, conn, c)
+	return l, nil
+}
+
+// Err returns the last connection error or nil. It can be called at any time to check whether
+// the listener is currently reconnecting.
+func (l *Listener) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+func (l *Listener) setErr(err error) {
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+}
+
+func (l *Listener) run(ctx context.Context, pool *pgxpool.Pool, chans []string, conn *pgx.Conn, c chan<- *Notification) {
+	defer close(c)
+	for n := 0; ; {
+		if conn != nil {
+			n = 0
+			l.setErr(nil)
+			err := l.wait(ctx, conn, c)
+			conn.Close(context.Background())
+			if ctx.Err() != nil {
+				return
+			}
+			l.setErr(err)
+		}
+		n++
+		select {
+		case <-time.After(ListenRetry.backoff(n)):
+		case <-ctx.Done():
+			return
+		}
+		var err error
+		conn, err = listenConn(ctx, pool, chans)
+		if err != nil {
+			l.setErr(err)
+		}
+	}
+}
+
+// wait sends notifications received by conn on c until ctx is canceled or the connection fails.
+func (l *Listener) wait(ctx context.Context, conn *pgx.Conn, c chan<- *Notification) error {
+	for {
+		pn, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		select {
+		case c <- &Notification{Channel: pn.Channel, PID: pn.PID, Payload: pn.Payload}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// listenConn returns a new connection hijacked from pool that listens to chans.
+func listenConn(ctx context.Context, pool *pgxpool.Pool, chans []string) (*pgx.Conn, error) {
+	pc, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listen connection: %w", err)
+	}
+	conn := pc.Hijack()
+	for _, ch := range chans {
+		_, err = TraceExec(ctx, conn, "LISTEN "+pgx.Identifier{ch}.Sanitize())
+		if err != nil {
+			conn.Close(context.Background())
+			return nil, fmt.Errorf("listen to %s: %w", ch, err)
+		}
+	}
+	return conn, nil
+}
+
+// Notify sends a notification on channel with the payload val encoded as json, which is also
+// valid xelf. A nil val sends an empty payload.
+func Notify(ctx context.Context, c C, channel string, val lit.Val) error {
+	var payload string
+	if val != nil {
+		raw, err := val.MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("notify payload: %w", err)
+		}
+		payload = string(raw)
+	}
+	_, err := TraceExec(ctx, c, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
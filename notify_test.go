@@ -0,0 +1,65 @@
+package dapgx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"xelf.org/xelf/lit"
+)
+
+func TestListen(t *testing.T) {
+	defer func(r Retry) { ListenRetry = r }(ListenRetry)
+	ListenRetry = Retry{Backoff: 10 * time.Millisecond, Limit: 50 * time.Millisecond}
+	db := testDB(t, "")
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l, err := Listen(ctx, db, "dapgx_test")
+	if err != nil {
+		t.Fatalf("listen %v", err)
+	}
+	err = Notify(ctx, db, "dapgx_test", lit.Int(1))
+	if err != nil {
+		t.Fatalf("notify %v", err)
+	}
+	select {
+	case n := <-l.C:
+		v, err := n.Lit()
+		if err != nil || n.Channel != "dapgx_test" || v.String() != "1" {
+			t.Errorf("want notification with payload 1 got %+v %v", n, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for notification")
+	}
+	// terminate the listening backend, the listener should reconnect and listen again
+	var killed int64
+	err = db.QueryRow(ctx, `SELECT count(pg_terminate_backend(pid)) FROM pg_stat_activity
+		WHERE query = 'LISTEN "dapgx_test"' AND pid <> pg_backend_pid()`).Scan(&killed)
+	if err != nil || killed != 1 {
+		t.Fatalf("terminate listener %d %v", killed, err)
+	}
+	// notifications sent while reconnecting are lost, so we notify until one arrives
+	timeout := time.After(5 * time.Second)
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case n := <-l.C:
+			if n.Payload != "2" {
+				t.Errorf("want payload 2 after reconnect got %q", n.Payload)
+			}
+			cancel()
+			for range l.C {
+			}
+			return
+		case <-tick.C:
+			err = Notify(ctx, db, "dapgx_test", lit.Int(2))
+			if err != nil {
+				t.Fatalf("notify %v", err)
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for notification after reconnect, last err %v", l.Err())
+		}
+	}
+}
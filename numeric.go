@@ -0,0 +1,252 @@
+package dapgx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgio"
+	"xelf.org/xelf/bfr"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Numeric is an exact decimal number literal backed by its canonical text representation.
+// We use it for postgres numeric values, that would lose precision as real literal.
+type Numeric struct{ lit.Str }
+
+// ParseNumeric returns a numeric literal for the decimal text s or an error.
+func ParseNumeric(s string) (Numeric, error) {
+	switch s {
+	case "NaN", "Infinity", "-Infinity":
+		return Numeric{lit.Str(s)}, nil
+	}
+	neg, ds, frac, exp, err := splitDecimal(s)
+	if err != nil {
+		return Numeric{}, err
+	}
+	// apply the exponent by moving the decimal point
+	digs := ds + frac
+	point := len(ds) + exp
+	for point < 0 {
+		digs = "0" + digs
+		point++
+	}
+	for point > len(digs) {
+		digs += "0"
+	}
+	ds, frac = strings.TrimLeft(digs[:point], "0"), digs[point:]
+	if ds == "" {
+		ds = "0"
+	}
+	var b strings.Builder
+	if neg && strings.Trim(ds+frac, "0") != "" {
+		b.WriteByte('-')
+	}
+	b.WriteString(ds)
+	if frac != "" {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return Numeric{lit.Str(b.String())}, nil
+}
+
+// ToNumeric converts the number or string literal v to a numeric literal or returns an error.
+func ToNumeric(v lit.Val) (Numeric, error) {
+	switch n := lit.Unwrap(v).(type) {
+	case Numeric:
+		return n, nil
+	case lit.Int:
+		return Numeric{lit.Str(strconv.FormatInt(int64(n), 10))}, nil
+	case lit.Str:
+		return ParseNumeric(string(n))
+	}
+	r, err := lit.ToReal(v)
+	if err != nil {
+		return Numeric{}, err
+	}
+	return ParseNumeric(strconv.FormatFloat(float64(r), 'f', -1, 64))
+}
+
+func (n Numeric) Type() typ.Type { return typ.Num }
+func (n Numeric) Zero() bool     { return strings.Trim(string(n.Str), "-0.") == "" }
+func (n Numeric) Value() lit.Val { return n }
+func (n Numeric) Mut() lit.Mut   { return &n }
+func (n Numeric) String() string { return string(n.Str) }
+func (n Numeric) Print(p *bfr.P) error {
+	_, err := p.WriteString(string(n.Str))
+	return err
+}
+func (n Numeric) MarshalJSON() ([]byte, error) { return []byte(n.Str), nil }
+
+func (n *Numeric) New() lit.Mut     { return new(Numeric) }
+func (n *Numeric) Ptr() interface{} { return n }
+func (n *Numeric) Assign(v lit.Val) error {
+	if v == nil || v.Nil() {
+		*n = Numeric{}
+		return nil
+	}
+	res, err := ToNumeric(v)
+	if err != nil {
+		return err
+	}
+	*n = res
+	return nil
+}
+func (n *Numeric) UnmarshalJSON(b []byte) error {
+	res, err := ParseNumeric(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*n = res
+	return nil
+}
+
+func splitDecimal(s string) (neg bool, ds, frac string, exp int, err error) {
+	str := s
+	if len(str) > 0 && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.Atoi(str[i+1:])
+		if err != nil {
+			return neg, "", "", 0, fmt.Errorf("invalid numeric exponent %q", s)
+		}
+		str, exp = str[:i], e
+	}
+	ds = str
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		ds, frac = str[:i], str[i+1:]
+	}
+	if ds == "" && frac == "" || !isDigits(ds) || !isDigits(frac) {
+		return neg, "", "", 0, fmt.Errorf("invalid numeric %q", s)
+	}
+	return neg, ds, frac, exp, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	numericPos  = 0x0000
+	numericNeg  = 0x4000
+	numericNaN  = 0xC000
+	numericPinf = 0xD000
+	numericNinf = 0xF000
+)
+
+// decodeNumericBin returns the canonical text for a binary numeric value.
+// The format is a header of ndigits, weight, sign and dscale followed by base 10000 digits.
+func decodeNumericBin(raw []byte) (string, error) {
+	if len(raw) < 8 {
+		return "", fmt.Errorf("invalid length for numeric: %d", len(raw))
+	}
+	ndigits := int(int16(binary.BigEndian.Uint16(raw)))
+	weight := int(int16(binary.BigEndian.Uint16(raw[2:])))
+	sign := binary.BigEndian.Uint16(raw[4:])
+	dscale := int(int16(binary.BigEndian.Uint16(raw[6:])))
+	if len(raw) != 8+ndigits*2 {
+		return "", fmt.Errorf("invalid length for numeric: %d", len(raw))
+	}
+	switch sign {
+	case numericNaN:
+		return "NaN", nil
+	case numericPinf:
+		return "Infinity", nil
+	case numericNinf:
+		return "-Infinity", nil
+	}
+	digit := func(i int) int {
+		if i < 0 || i >= ndigits {
+			return 0
+		}
+		return int(int16(binary.BigEndian.Uint16(raw[8+i*2:])))
+	}
+	var b strings.Builder
+	if sign == numericNeg && ndigits > 0 {
+		b.WriteByte('-')
+	}
+	if weight < 0 {
+		b.WriteByte('0')
+	}
+	for i := 0; i <= weight; i++ {
+		if i == 0 {
+			b.WriteString(strconv.Itoa(digit(i)))
+		} else {
+			fmt.Fprintf(&b, "%04d", digit(i))
+		}
+	}
+	if dscale > 0 {
+		frac := make([]byte, 0, dscale+4)
+		for i := weight + 1; len(frac) < dscale; i++ {
+			frac = append(frac, fmt.Sprintf("%04d", digit(i))...)
+		}
+		b.WriteByte('.')
+		b.Write(frac[:dscale])
+	}
+	return b.String(), nil
+}
+
+// appendNumericBin appends the binary numeric representation of the canonical text s to b.
+func appendNumericBin(b []byte, s string) ([]byte, error) {
+	switch s {
+	case "NaN":
+		return appendNumericHead(b, 0, 0, numericNaN, 0), nil
+	case "Infinity":
+		return appendNumericHead(b, 0, 0, numericPinf, 0), nil
+	case "-Infinity":
+		return appendNumericHead(b, 0, 0, numericNinf, 0), nil
+	}
+	neg, ds, frac, exp, err := splitDecimal(s)
+	if err != nil || exp != 0 {
+		return nil, fmt.Errorf("invalid numeric %q", s)
+	}
+	dscale := len(frac)
+	// pad integer and fraction digits to align with the base 10000 digit groups
+	if n := len(ds) % 4; n != 0 {
+		ds = strings.Repeat("0", 4-n) + ds
+	}
+	if n := len(frac) % 4; n != 0 {
+		frac += strings.Repeat("0", 4-n)
+	}
+	all := ds + frac
+	groups := make([]int16, 0, len(all)/4)
+	for i := 0; i < len(all); i += 4 {
+		n, _ := strconv.Atoi(all[i : i+4])
+		groups = append(groups, int16(n))
+	}
+	weight := len(ds)/4 - 1
+	for len(groups) > 0 && groups[0] == 0 {
+		groups = groups[1:]
+		weight--
+	}
+	for len(groups) > 0 && groups[len(groups)-1] == 0 {
+		groups = groups[:len(groups)-1]
+	}
+	sign := uint16(numericPos)
+	if len(groups) == 0 {
+		weight = 0
+	} else if neg {
+		sign = numericNeg
+	}
+	b = appendNumericHead(b, len(groups), weight, sign, dscale)
+	for _, g := range groups {
+		b = pgio.AppendInt16(b, g)
+	}
+	return b, nil
+}
+
+func appendNumericHead(b []byte, ndigits, weight int, sign uint16, dscale int) []byte {
+	b = pgio.AppendInt16(b, int16(ndigits))
+	b = pgio.AppendInt16(b, int16(weight))
+	b = pgio.AppendUint16(b, sign)
+	return pgio.AppendInt16(b, int16(dscale))
+}
@@ -2,8 +2,6 @@ package qrypgx
 
 import (
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"xelf.org/dapgx"
@@ -19,6 +17,10 @@ type Backend struct {
 	DB *pgxpool.Pool
 	*dom.Project
 	*mig.Version
+	// Types is an optional registry of user defined types for the pool.
+	Types *dapgx.Types
+	// Time are optional date and timestamp codec options for the pool.
+	Time   *dapgx.TimeOpts
 	tables map[string]*dom.Model
 }
 
@@ -61,10 +63,6 @@ func (b *Backend) execQuery(p *exp.Prog, q *Query) error {
 	if err != nil {
 		return err
 	}
-	start := time.Now()
-	defer func() {
-		log.Printf("query %s took %s", qs, time.Now().Sub(start))
-	}()
 	var args []lit.Val
 	if len(ps) != 0 {
 		args = make([]lit.Val, 0, len(ps))
@@ -76,10 +74,11 @@ func (b *Backend) execQuery(p *exp.Prog, q *Query) error {
 			return fmt.Errorf("unexpected external param %+v", p)
 		}
 	}
-	return b.DB.AcquireFunc(p.Ctx, func(c *pgxpool.Conn) error {
-		rows, err := dapgx.Query(p.Ctx, c.Conn(), qs, args)
+	ctx := dapgx.WithTimeOpts(dapgx.WithTypes(p.Ctx, b.Types), b.Time)
+	return b.DB.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		rows, err := dapgx.Query(ctx, c.Conn(), qs, args)
 		if err != nil {
-			return fmt.Errorf("query %s: %w", qs, err)
+			return fmt.Errorf("query %s: %w", qs, dapgx.TranslateErr(b.Project, err))
 		}
 		defer rows.Close()
 		mut := p.Reg.ZeroWrap(q.Res)
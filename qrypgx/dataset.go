@@ -8,6 +8,7 @@ import (
 
 	pgx "github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"xelf.org/dapgx"
 	"xelf.org/daql/dom"
 	"xelf.org/daql/mig"
 	"xelf.org/xelf/lit"
@@ -51,8 +52,7 @@ func openRowsIter(db *pgxpool.Pool, m *dom.Model) (*rowsIter, error) {
 	}
 	b.WriteString(" FROM ")
 	b.WriteString(m.Qualified())
-	ctx := context.Background()
-	rows, err := db.Query(ctx, b.String())
+	rows, err := dapgx.TraceQuery(context.Background(), db, b.String())
 	if err != nil {
 		return nil, err
 	}
@@ -9,11 +9,15 @@ import (
 	"xelf.org/daql/qry"
 	"xelf.org/xelf/exp"
 	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
 )
 
 func genQuery(pr *dom.Project, p *exp.Prog, q *Query) (string, []dapgx.Param, error) {
 	b := &strings.Builder{}
 	w := dapgx.NewWriter(b, pr, p, &jobTranslator{q.Alias})
+	for _, as := range q.Alias {
+		w.Reserve(as)
+	}
 	err := genSelect(w, p, q.Alias, q)
 	if err != nil {
 		return "", nil, err
@@ -135,6 +139,22 @@ func (jt *jobTranslator) Translate(p *exp.Prog, env exp.Env, s *exp.Sym) (string
 	return "", nil, fmt.Errorf("no selection for %q", s.Sym)
 }
 
+func (jt *jobTranslator) TranslatePath(p *exp.Prog, env exp.Env, s *exp.Sym) (string, typ.Type, []string, error) {
+	j := qry.FindJob(s.Env)
+	if j == nil || len(s.Sym) < 2 || s.Sym[0] != '.' || s.Sym[1] == '.' {
+		return "", typ.Void, nil, nil
+	}
+	keys := dapgx.PathKeys(s.Sym)
+	if len(keys) < 2 {
+		return "", typ.Void, nil, nil
+	}
+	f, _ := j.Field(keys[0])
+	if f == nil {
+		return "", typ.Void, nil, fmt.Errorf("no selection for %q", s.Sym)
+	}
+	return jt.ColRef(j, f.Key), f.Type, keys[1:], nil
+}
+
 func genCommon(w *dapgx.Writer, j *qry.Job) error {
 	if len(j.Ord) > 0 {
 		w.WriteString(" ORDER BY ")
@@ -146,7 +166,14 @@ func genCommon(w *dapgx.Writer, j *qry.Job) error {
 			if key[0] == '.' {
 				key = key[1:]
 			}
-			w.WriteString(key)
+			if keys := dapgx.PathKeys(key); len(keys) > 1 {
+				err := genOrdPath(w, j, keys)
+				if err != nil {
+					return err
+				}
+			} else {
+				w.WriteString(key)
+			}
 			if ord.Desc {
 				w.WriteString(" DESC")
 			}
@@ -164,6 +191,25 @@ func genCommon(w *dapgx.Writer, j *qry.Job) error {
 	}
 	return nil
 }
+
+// genOrdPath writes the ordering by a path into a jsonb field. The path value is cast to the
+// selected type if the field type declares it.
+func genOrdPath(w *dapgx.Writer, j *qry.Job, keys []string) error {
+	f, _ := j.Field(keys[0])
+	if f == nil {
+		return fmt.Errorf("no selection for order %q", strings.Join(keys, "."))
+	}
+	col := f.Key
+	if jt, ok := w.Translator.(*jobTranslator); ok {
+		col = jt.ColRef(j, f.Key)
+	}
+	t, err := typ.Select(f.Type, strings.Join(keys[1:], "."))
+	if err != nil {
+		t = typ.Any
+	}
+	return dapgx.WritePath(w, col, f.Type, keys[1:], t)
+}
+
 func genFrom(w *dapgx.Writer, a Alias, q *Query, i int) error {
 	if i > 0 {
 		w.WriteString(", ")
@@ -0,0 +1,319 @@
+package dapgx
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jackc/pgio"
+	"github.com/jackc/pgtype"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Range values are represented as xelf objects with the keys lower, upper, lowerInc, upperInc
+// and empty. Unbounded lower or upper values are null. We use the same objects in a list for
+// multirange values.
+
+// rangeElems maps range and multirange oids to their element oid.
+var rangeElems = map[uint32]uint32{
+	pgtype.Int4rangeOID:         pgtype.Int4OID,
+	pgtype.Int8rangeOID:         pgtype.Int8OID,
+	pgtype.NumrangeOID:          pgtype.NumericOID,
+	pgtype.TsrangeOID:           pgtype.TimestampOID,
+	pgtype.TstzrangeOID:         pgtype.TimestamptzOID,
+	pgtype.DaterangeOID:         pgtype.DateOID,
+	4451 /*Int4multirangeOID*/ : pgtype.Int4rangeOID,
+	4536 /*Int8multirangeOID*/ : pgtype.Int8rangeOID,
+	4532 /*NummultirangeOID*/ :  pgtype.NumrangeOID,
+	4533 /*TsmultirangeOID*/ :   pgtype.TsrangeOID,
+	4534 /*TstzmultirangeOID*/ : pgtype.TstzrangeOID,
+	4535 /*DatemultirangeOID*/ : pgtype.DaterangeOID,
+}
+
+// rangeArrays maps range array oids to the range oid.
+var rangeArrays = map[uint32]uint32{
+	3905 /*Int4rangeArrayOID*/ : pgtype.Int4rangeOID,
+	3927 /*Int8rangeArrayOID*/ : pgtype.Int8rangeOID,
+	3907 /*NumrangeArrayOID*/ :  pgtype.NumrangeOID,
+	pgtype.TsrangeArrayOID:      pgtype.TsrangeOID,
+	pgtype.TstzrangeArrayOID:    pgtype.TstzrangeOID,
+	3913 /*DaterangeArrayOID*/ : pgtype.DaterangeOID,
+}
+
+// MakeRange returns a new range object for the given bounds. Nil bounds are unbounded.
+func MakeRange(lower, upper lit.Val, lowerInc, upperInc, empty bool) *lit.Obj {
+	if lower == nil {
+		lower = lit.Null{}
+	}
+	if upper == nil {
+		upper = lit.Null{}
+	}
+	return lit.MakeObj(lit.Keyed{
+		{Key: "lower", Val: lower},
+		{Key: "upper", Val: upper},
+		{Key: "lowerInc", Val: lit.Bool(lowerInc)},
+		{Key: "upperInc", Val: lit.Bool(upperInc)},
+		{Key: "empty", Val: lit.Bool(empty)},
+	})
+}
+
+func rangeDecs(txt, bin Decoder) DecoderPair {
+	return DecoderPair{rangeTextDec(txt), rangeBinDec(bin)}
+}
+func rangeTextDec(eldec Decoder) Decoder {
+	return func(raw []byte) (lit.Val, error) {
+		r, err := pgtype.ParseUntypedTextRange(string(raw))
+		if err != nil {
+			return nil, err
+		}
+		if r.LowerType == pgtype.Empty {
+			return MakeRange(nil, nil, false, false, true), nil
+		}
+		var lower, upper lit.Val
+		if r.LowerType != pgtype.Unbounded {
+			lower, err = eldec([]byte(r.Lower))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if r.UpperType != pgtype.Unbounded {
+			upper, err = eldec([]byte(r.Upper))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return MakeRange(lower, upper,
+			r.LowerType == pgtype.Inclusive, r.UpperType == pgtype.Inclusive, false), nil
+	}
+}
+func rangeBinDec(eldec Decoder) Decoder {
+	return func(raw []byte) (lit.Val, error) {
+		r, err := pgtype.ParseUntypedBinaryRange(raw)
+		if err != nil {
+			return nil, err
+		}
+		if r.LowerType == pgtype.Empty {
+			return MakeRange(nil, nil, false, false, true), nil
+		}
+		var lower, upper lit.Val
+		if r.LowerType != pgtype.Unbounded {
+			lower, err = eldec(r.Lower)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if r.UpperType != pgtype.Unbounded {
+			upper, err = eldec(r.Upper)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return MakeRange(lower, upper,
+			r.LowerType == pgtype.Inclusive, r.UpperType == pgtype.Inclusive, false), nil
+	}
+}
+
+func multirangeDecs(rng DecoderPair) DecoderPair {
+	return DecoderPair{multirangeTextDec(rng.Text), multirangeBinDec(rng.Binary)}
+}
+func multirangeTextDec(rngdec Decoder) Decoder {
+	return func(raw []byte) (lit.Val, error) {
+		mr, err := pgtype.ParseUntypedTextMultirange(string(raw))
+		if err != nil {
+			return nil, err
+		}
+		vals := make([]lit.Val, 0, len(mr.Elements))
+		for _, el := range mr.Elements {
+			val, err := rngdec([]byte(el))
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return lit.NewList(typ.Data, vals...), nil
+	}
+}
+func multirangeBinDec(rngdec Decoder) Decoder {
+	return func(raw []byte) (lit.Val, error) {
+		if len(raw) < 4 {
+			return nil, fmt.Errorf("invalid length for multirange: %d", len(raw))
+		}
+		n := int(binary.BigEndian.Uint32(raw))
+		off := 4
+		vals := make([]lit.Val, 0, n)
+		for i := 0; i < n; i++ {
+			if len(raw) < off+4 {
+				return nil, fmt.Errorf("invalid length for multirange: %d", len(raw))
+			}
+			size := int(binary.BigEndian.Uint32(raw[off:]))
+			off += 4
+			if len(raw) < off+size {
+				return nil, fmt.Errorf("invalid length for multirange: %d", len(raw))
+			}
+			val, err := rngdec(raw[off : off+size])
+			if err != nil {
+				return nil, err
+			}
+			off += size
+			vals = append(vals, val)
+		}
+		return lit.NewList(typ.Data, vals...), nil
+	}
+}
+
+// rangeBounds reads the range object k and returns the bounds or an error.
+func rangeBounds(k lit.Keyr) (lower, upper lit.Val, lowerInc, upperInc, empty bool, err error) {
+	flag := func(key string, def bool) bool {
+		v, err := k.Key(key)
+		if err != nil || v == nil || v.Nil() {
+			return def
+		}
+		return !v.Zero()
+	}
+	if empty = flag("empty", false); empty {
+		return nil, nil, false, false, true, nil
+	}
+	lower, err = k.Key("lower")
+	if err != nil {
+		return
+	}
+	upper, err = k.Key("upper")
+	if err != nil {
+		return
+	}
+	if lower != nil && lower.Nil() {
+		lower = nil
+	}
+	if upper != nil && upper.Nil() {
+		upper = nil
+	}
+	return lower, upper, flag("lowerInc", true), flag("upperInc", false), false, nil
+}
+
+// WrapRange encodes a range object for a postgres range type with the element oid Oid.
+type WrapRange struct {
+	lit.Keyr
+	Oid uint32
+}
+
+func (w WrapRange) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	lower, upper, linc, uinc, empty, err := rangeBounds(w.Keyr)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return append(b, "empty"...), nil
+	}
+	if linc && lower != nil {
+		b = append(b, '[')
+	} else {
+		b = append(b, '(')
+	}
+	for i, v := range [2]lit.Val{lower, upper} {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if v == nil {
+			continue
+		}
+		enc, err := FieldEncoder(w.Oid, v)
+		if err != nil {
+			return nil, err
+		}
+		c, err := enc.EncodeText(ci, nil)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, '"')
+		for _, r := range c {
+			if r == '"' || r == '\\' {
+				b = append(b, '\\')
+			}
+			b = append(b, r)
+		}
+		b = append(b, '"')
+	}
+	if uinc && upper != nil {
+		return append(b, ']'), nil
+	}
+	return append(b, ')'), nil
+}
+func (w WrapRange) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	lower, upper, linc, uinc, empty, err := rangeBounds(w.Keyr)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return append(b, 1), nil
+	}
+	var flags byte
+	if lower == nil {
+		flags |= 8
+	} else if linc {
+		flags |= 2
+	}
+	if upper == nil {
+		flags |= 16
+	} else if uinc {
+		flags |= 4
+	}
+	b = append(b, flags)
+	for _, v := range [2]lit.Val{lower, upper} {
+		if v == nil {
+			continue
+		}
+		enc, err := FieldEncoder(w.Oid, v)
+		if err != nil {
+			return nil, err
+		}
+		mark := len(b)
+		b = append(b, 0, 0, 0, 0)
+		b, err = enc.EncodeBinary(ci, b)
+		if err != nil {
+			return nil, err
+		}
+		pgio.SetInt32(b[mark:], int32(len(b[mark+4:])))
+	}
+	return b, nil
+}
+
+// WrapMultirange encodes a list of range objects for a postgres multirange type with the range
+// oid Oid.
+type WrapMultirange struct {
+	lit.Idxr
+	Oid uint32
+}
+
+func (w WrapMultirange) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	b = append(b, '{')
+	err := w.IterIdx(func(idx int, v lit.Val) error {
+		if idx > 0 {
+			b = append(b, ',')
+		}
+		enc, err := FieldEncoder(w.Oid, v)
+		if err != nil {
+			return err
+		}
+		b, err = enc.EncodeText(ci, b)
+		return err
+	})
+	return append(b, '}'), err
+}
+func (w WrapMultirange) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	b = pgio.AppendInt32(b, int32(w.Len()))
+	err := w.IterIdx(func(idx int, v lit.Val) error {
+		enc, err := FieldEncoder(w.Oid, v)
+		if err != nil {
+			return err
+		}
+		mark := len(b)
+		b = append(b, 0, 0, 0, 0)
+		b, err = enc.EncodeBinary(ci, b)
+		if err != nil {
+			return err
+		}
+		pgio.SetInt32(b[mark:], int32(len(b[mark+4:])))
+		return nil
+	})
+	return b, err
+}
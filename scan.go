@@ -2,10 +2,12 @@ package dapgx
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgtype"
 	pgx "github.com/jackc/pgx/v4"
 
+	"xelf.org/xelf/cor"
 	"xelf.org/xelf/lit"
 	"xelf.org/xelf/typ"
 )
@@ -16,6 +18,7 @@ func ScanOne(reg lit.Regs, scal bool, mut lit.Mut, rows pgx.Rows) error {
 		if err != nil {
 			return err
 		}
+		s.Reg = &reg
 		err = s.Scan(mut)
 		if err != nil {
 			return err
@@ -43,6 +46,7 @@ func ScanMany(reg lit.Regs, scal bool, mut lit.Mut, rows pgx.Rows) (err error) {
 			if err != nil {
 				return err
 			}
+			s.Reg = &reg
 		}
 		err = s.Scan(el)
 		if err != nil {
@@ -58,7 +62,13 @@ func ScanMany(reg lit.Regs, scal bool, mut lit.Mut, rows pgx.Rows) (err error) {
 
 // Scanner is a simplified xelf-aware Scanner for pgx rows. it avoids some hacks on my end,
 // alleviate many extra type checks and has better null handling for my use-case.
+//
+// Column names with dots like "cat.name" are parsed as path and assigned to nested keyrs. This
+// applies to all non-scalar scans including ScanOne and ScanMany, so a column alias that only
+// contains a dot by accident must be renamed to keep it as a flat key.
 type Scanner struct {
+	// Reg is used to create missing intermediate values for nested column paths.
+	Reg  *lit.Regs
 	rows pgx.Rows
 	scal bool
 	cols []scancol
@@ -66,6 +76,7 @@ type Scanner struct {
 
 type scancol struct {
 	key    string
+	path   cor.Path
 	decode Decoder
 }
 
@@ -74,14 +85,42 @@ func NewScanner(scal bool, rows pgx.Rows) (*Scanner, error) {
 	if scal && len(fds) != 1 {
 		return nil, fmt.Errorf("unexpected number of scalar fields, got %d", len(fds))
 	}
+	ts, to := RowsTypes(rows), RowsTimeOpts(rows)
 	cols := make([]scancol, len(fds))
 	for i, fd := range fds {
-		dec := FieldDecoder(fd.DataTypeOID, fd.Format == pgtype.BinaryFormatCode)
-		cols[i] = scancol{string(fd.Name), dec}
+		cols[i] = scancol{key: string(fd.Name), decode: fieldDecoder(ts, to, fd.DataTypeOID, fd.Format)}
+		if !scal && strings.IndexByte(cols[i].key, '.') > 0 {
+			path, err := colPath(cols[i].key)
+			if err != nil {
+				return nil, err
+			}
+			cols[i].path = path
+		}
 	}
 	return &Scanner{rows: rows, scal: scal, cols: cols}, nil
 }
 
+// SetTimeOpts changes the decoders of date and timestamp columns to use the time options o.
+func (s *Scanner) SetTimeOpts(o *TimeOpts) {
+	ts := RowsTypes(s.rows)
+	for i, fd := range s.rows.FieldDescriptions() {
+		s.cols[i].decode = fieldDecoder(ts, o, fd.DataTypeOID, fd.Format)
+	}
+}
+
+func fieldDecoder(ts *Types, to *TimeOpts, oid uint32, format int16) Decoder {
+	bin := format == pgtype.BinaryFormatCode
+	if to != nil {
+		if decs := to.decs(ts.Resolve(oid)); decs.Text != nil {
+			if bin {
+				return decs.Binary
+			}
+			return decs.Text
+		}
+	}
+	return ts.FieldDecoder(oid, bin)
+}
+
 func (s *Scanner) Scan(m lit.Mut) (err error) {
 	vals := s.rows.RawValues()
 	if len(vals) != len(s.cols) {
@@ -107,6 +146,8 @@ func (s *Scanner) Scan(m lit.Mut) (err error) {
 		}
 		if s.scal {
 			err = m.Assign(val)
+		} else if col.path != nil {
+			err = s.setPath(k, col.path, val)
 		} else {
 			err = k.SetKey(col.key, val)
 		}
@@ -116,3 +157,64 @@ func (s *Scanner) Scan(m lit.Mut) (err error) {
 	}
 	return nil
 }
+
+func colPath(key string) (cor.Path, error) {
+	path, err := cor.ParsePath(key)
+	if err != nil {
+		return nil, fmt.Errorf("column path %s: %w", key, err)
+	}
+	for _, seg := range path {
+		if seg.Key == "" {
+			return nil, fmt.Errorf("column path %s: only key segments supported", key)
+		}
+	}
+	return path, nil
+}
+
+// setPath assigns val to the key path in k. Missing intermediate values are created with the
+// registry, unless val is null.
+func (s *Scanner) setPath(k lit.Keyr, path cor.Path, val lit.Val) error {
+	key := path[0].Key
+	if len(path) == 1 {
+		return k.SetKey(key, val)
+	}
+	cur, err := k.Key(key)
+	if err != nil {
+		return err
+	}
+	var sub lit.Keyr
+	if cur != nil && !cur.Nil() {
+		sub, _ = lit.Unwrap(cur).(lit.Keyr)
+	}
+	if sub == nil {
+		if val == nil || val.Nil() {
+			return nil
+		}
+		reg := s.Reg
+		if reg == nil {
+			reg = &lit.Regs{}
+		}
+		m := reg.Zero(typ.Deopt(fieldType(k.Type(), key)))
+		var ok bool
+		if sub, ok = m.(lit.Keyr); !ok {
+			return fmt.Errorf("scan column %s expect keyr got %T", path, m)
+		}
+	}
+	err = s.setPath(sub, path[1:], val)
+	if err != nil {
+		return err
+	}
+	return k.SetKey(key, sub)
+}
+
+// fieldType returns the type of the field key in object type t or dict.
+func fieldType(t typ.Type, key string) typ.Type {
+	if pb, ok := typ.Deopt(t).Body.(*typ.ParamBody); ok {
+		for _, p := range pb.Params {
+			if p.Key == key {
+				return p.Type
+			}
+		}
+	}
+	return typ.Dict
+}
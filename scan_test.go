@@ -0,0 +1,116 @@
+package dapgx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgtype"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// scanRows is a single text row with columns given as name, oid and string or nil triples.
+type scanRows struct {
+	pgx.Rows
+	fds  []pgproto3.FieldDescription
+	vals [][]byte
+}
+
+func (r *scanRows) FieldDescriptions() []pgproto3.FieldDescription { return r.fds }
+func (r *scanRows) RawValues() [][]byte                            { return r.vals }
+
+func testRows(cols ...interface{}) *scanRows {
+	r := &scanRows{}
+	for i := 0; i < len(cols); i += 3 {
+		r.fds = append(r.fds, pgproto3.FieldDescription{
+			Name: []byte(cols[i].(string)), DataTypeOID: uint32(cols[i+1].(int)),
+		})
+		var raw []byte
+		if v, ok := cols[i+2].(string); ok {
+			raw = []byte(v)
+		}
+		r.vals = append(r.vals, raw)
+	}
+	return r
+}
+
+func objType(ps ...typ.Param) typ.Type {
+	return typ.Type{Kind: knd.Obj, Body: &typ.ParamBody{Params: ps}}
+}
+
+func TestScanPath(t *testing.T) {
+	cat := objType(typ.Param{Key: "name", Type: typ.Str})
+	tests := []struct {
+		name string
+		t    typ.Type
+		rows *scanRows
+		want string
+	}{
+		{"nested obj", objType(typ.Param{Key: "id", Type: typ.Int}, typ.Param{Key: "cat", Type: cat}),
+			testRows("id", pgtype.Int8OID, "1", "cat.name", pgtype.TextOID, "a"),
+			`{id:1 cat:{name:'a'}}`},
+		{"null intermediate", objType(typ.Param{Key: "id", Type: typ.Int},
+			typ.Param{Key: "cat", Type: typ.Opt(cat)}),
+			testRows("id", pgtype.Int8OID, "1", "cat.name", pgtype.TextOID, nil),
+			`{id:1 cat:null}`},
+		{"registry zero", objType(typ.Param{Key: "id", Type: typ.Int},
+			typ.Param{Key: "cat", Type: typ.Opt(cat)}),
+			testRows("id", pgtype.Int8OID, "1", "cat.name", pgtype.TextOID, "a"),
+			`{id:1 cat:{name:'a'}}`},
+		{"dict", typ.Dict,
+			testRows("cat.name", pgtype.TextOID, "a", "cat.id", pgtype.Int8OID, nil),
+			`{cat:{name:'a' id:null}}`},
+	}
+	for _, test := range tests {
+		reg := &lit.Regs{}
+		s, err := NewScanner(false, test.rows)
+		if err != nil {
+			t.Errorf("%s new scanner: %v", test.name, err)
+			continue
+		}
+		s.Reg = reg
+		m := reg.Zero(test.t)
+		err = s.Scan(m)
+		if err != nil {
+			t.Errorf("%s scan: %v", test.name, err)
+			continue
+		}
+		if got := m.String(); got != test.want {
+			t.Errorf("%s want %s got %s", test.name, test.want, got)
+		}
+		if test.name != "registry zero" {
+			continue
+		}
+		// the missing intermediate is created with the field type instead of a dict
+		sub, err := m.(lit.Keyr).Key("cat")
+		if err != nil {
+			t.Fatalf("%s key: %v", test.name, err)
+		}
+		if _, ok := lit.Unwrap(sub).(*lit.Obj); !ok {
+			t.Errorf("%s want obj got %T", test.name, lit.Unwrap(sub))
+		}
+	}
+}
+
+func TestStrictScanPath(t *testing.T) {
+	cat := objType(typ.Param{Key: "name", Type: typ.Str})
+	st := objType(typ.Param{Key: "id", Type: typ.Int}, typ.Param{Key: "cat", Type: cat})
+	_, err := NewStrictScanner(st, false,
+		testRows("id", pgtype.Int8OID, "1", "cat.name", pgtype.TextOID, "a"))
+	if err != nil {
+		t.Errorf("want match got %v", err)
+	}
+	_, err = NewStrictScanner(st, false,
+		testRows("id", pgtype.Int8OID, "1", "cat.name", pgtype.BoolOID, "t"))
+	var me *MismatchError
+	if !errors.As(err, &me) {
+		t.Fatalf("want mismatch error got %v", err)
+	}
+	if len(me.Errs) != 1 || !strings.HasPrefix(me.Errs[0], "column cat.name with type oid 16") {
+		t.Errorf("want cat.name mismatch got %v", me.Errs)
+	}
+}
@@ -0,0 +1,316 @@
+package dapgx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgtype"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// SQLDB is the query interface of *sql.DB, *sql.Conn and *sql.Tx.
+type SQLDB interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+// SQL adapts a database/sql connection to PC, so that Query, Exec and the scanners can be used
+// with drivers like pgx stdlib or wrappers thereof.
+//
+// Database/sql does not report parameter types, so arguments are encoded as text using the type
+// of the literal. Result values are formatted as postgres text and decoded with the dapgx
+// decoders using the type names reported by the driver. Unknown types are decoded as text.
+type SQL struct{ DB SQLDB }
+
+var _ PC = SQL{}
+
+// Prepare returns a statement description with unknown parameter types and the sql as name.
+// It does not prepare anything, database/sql drivers handle prepared statements themselves.
+func (s SQL) Prepare(_ context.Context, _, sql string) (*pgconn.StatementDescription, error) {
+	n, err := paramCount(sql)
+	if err != nil {
+		return nil, err
+	}
+	return &pgconn.StatementDescription{Name: sql, SQL: sql, ParamOIDs: make([]uint32, n)}, nil
+}
+
+func (s SQL) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	args, err := sqlArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	ts := TypesFrom(ctx)
+	fds := make([]pgproto3.FieldDescription, len(cts))
+	for i, ct := range cts {
+		fds[i] = pgproto3.FieldDescription{
+			Name:        []byte(ct.Name()),
+			DataTypeOID: sqlTypeOid(ts, ct.DatabaseTypeName()),
+			Format:      pgtype.TextFormatCode,
+		}
+	}
+	return &sqlRows{rows: rows, fds: fds}, nil
+}
+
+func (s SQL) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, err := s.Query(ctx, sql, args...)
+	return sqlRow{rows, err}
+}
+
+func (s SQL) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	args, err := sqlArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.DB.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		// the tag is only used for the affected rows, that some drivers do not report
+		return nil, nil
+	}
+	return pgconn.CommandTag(fmt.Sprintf("SQL %d", n)), nil
+}
+
+func (s SQL) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, fmt.Errorf("copy from not supported by database/sql")
+}
+
+// sqlArgs returns args with encoders replaced by their text encoding.
+func sqlArgs(args []interface{}) ([]interface{}, error) {
+	res := make([]interface{}, len(args))
+	for i, arg := range args {
+		enc, ok := arg.(Encoder)
+		if !ok {
+			res[i] = arg
+			continue
+		}
+		raw, err := enc.EncodeText(nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("encode param %d: %w", i+1, err)
+		}
+		if raw != nil {
+			res[i] = string(raw)
+		}
+	}
+	return res, nil
+}
+
+// argOid returns a type oid for params of unknown type based on the type of literal v.
+func argOid(v lit.Val) uint32 {
+	if v == nil || v.Nil() {
+		return pgtype.TextOID
+	}
+	return typOid(v.Type())
+}
+
+func typOid(t typ.Type) uint32 {
+	t = typ.Deopt(t)
+	switch k := t.Kind; {
+	case k&knd.Bool != 0:
+		return pgtype.BoolOID
+	case k&knd.Int != 0:
+		return pgtype.Int8OID
+	case k&knd.Real != 0:
+		return pgtype.Float8OID
+	case k&knd.Num != 0:
+		return pgtype.NumericOID
+	case k&knd.Raw != 0:
+		return pgtype.ByteaOID
+	case k&knd.UUID != 0:
+		return pgtype.UUIDOID
+	case k&knd.Time != 0:
+		return pgtype.TimestamptzOID
+	case k&knd.Span != 0:
+		return pgtype.IntervalOID
+	case k&knd.Char != 0:
+		return pgtype.TextOID
+	case k&knd.List != 0:
+		el := typOid(typ.ContEl(t))
+		for arr, oid := range arrayElems {
+			if oid == el {
+				return arr
+			}
+		}
+	}
+	return pgtype.JSONBOID
+}
+
+// sqlTypeOid returns the type oid for a database type name reported by a database/sql driver.
+func sqlTypeOid(ts *Types, name string) uint32 {
+	name = strings.ToLower(name)
+	if oid := ts.Oid(name); oid != 0 {
+		return oid
+	}
+	if dt, ok := sqlConnInfo.DataTypeForName(name); ok {
+		return dt.OID
+	}
+	return pgtype.TextOID
+}
+
+var sqlConnInfo = pgtype.NewConnInfo()
+
+// paramCount returns the highest positional parameter number in sql.
+func paramCount(sql string) (n int, err error) {
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		end := i + 1
+		switch {
+		case c == '\'' || c == '"':
+			end = skipQuoted(sql, i, c, c == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e'))
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end = strings.IndexByte(sql[i:], '\n'); end < 0 {
+				end = len(sql)
+			} else {
+				end += i
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end = skipComment(sql, i)
+		case c == '$':
+			d := i + 1
+			for d < len(sql) && sql[d] >= '0' && sql[d] <= '9' {
+				d++
+			}
+			if d > i+1 {
+				p, _ := strconv.Atoi(sql[i+1 : d])
+				if p > n {
+					n = p
+				}
+				end = d
+				break
+			}
+			if e := identEnd(sql, d); e < len(sql) && sql[e] == '$' {
+				// dollar-quoted string
+				tag := sql[i : e+1]
+				if end = strings.Index(sql[e+1:], tag); end >= 0 {
+					end += e + 1 + len(tag)
+				}
+			}
+		}
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated quote or comment at %d", i)
+		}
+		i = end
+	}
+	return n, nil
+}
+
+// sqlRows implements pgx.Rows for database/sql rows. Raw values are formatted as postgres text.
+type sqlRows struct {
+	rows *sql.Rows
+	fds  []pgproto3.FieldDescription
+	err  error
+}
+
+func (r *sqlRows) Close() { r.rows.Close() }
+func (r *sqlRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Err()
+}
+func (r *sqlRows) CommandTag() pgconn.CommandTag                  { return nil }
+func (r *sqlRows) FieldDescriptions() []pgproto3.FieldDescription { return r.fds }
+func (r *sqlRows) Next() bool                                     { return r.err == nil && r.rows.Next() }
+func (r *sqlRows) Scan(dest ...interface{}) error                 { return r.rows.Scan(dest...) }
+
+func (r *sqlRows) Values() ([]interface{}, error) {
+	vals := make([]interface{}, len(r.fds))
+	ptrs := make([]interface{}, len(vals))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := r.rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+func (r *sqlRows) RawValues() [][]byte {
+	vals, err := r.Values()
+	if err != nil {
+		r.err = err
+		return nil
+	}
+	res := make([][]byte, len(vals))
+	for i, v := range vals {
+		res[i] = sqlText(r.fds[i].DataTypeOID, v)
+	}
+	return res
+}
+
+// sqlText returns the postgres text format of the driver value v of a column with type oid.
+func sqlText(oid uint32, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if oid == pgtype.ByteaOID {
+			return []byte(`\x` + hex.EncodeToString(v))
+		}
+		return v
+	case string:
+		return []byte(v)
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64)
+	case bool:
+		if v {
+			return []byte{'t'}
+		}
+		return []byte{'f'}
+	case time.Time:
+		switch oid {
+		case pgtype.DateOID:
+			return v.AppendFormat(nil, "2006-01-02")
+		case pgtype.TimestampOID:
+			return v.AppendFormat(nil, "2006-01-02 15:04:05.999999")
+		}
+		return v.AppendFormat(nil, "2006-01-02 15:04:05.999999Z07:00")
+	}
+	return []byte(fmt.Sprint(v))
+}
+
+// sqlRow implements pgx.Row for database/sql rows.
+type sqlRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r sqlRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	return r.rows.Err()
+}
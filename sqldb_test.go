@@ -0,0 +1,21 @@
+package dapgx
+
+import "testing"
+
+func TestParamCount(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want int
+	}{
+		{"select 1", 0},
+		{"select $1, $2::int", 2},
+		{"select $2 where a = $1", 2},
+		{"select '$3', \"$4\", $tag$ $5 $tag$ -- $6\n, $1 /* $7 */", 1},
+	}
+	for _, test := range tests {
+		got, err := paramCount(test.sql)
+		if err != nil || got != test.want {
+			t.Errorf("%q want %d got %d %v", test.sql, test.want, got, err)
+		}
+	}
+}
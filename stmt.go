@@ -0,0 +1,192 @@
+package dapgx
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+)
+
+// StmtCacheSize is the maximum number of prepared statements cached per connection.
+// The least recently used statements are deallocated when the limit is exceeded.
+var StmtCacheSize = 256
+
+// stmtCache is a lru cache of the prepared statements of one connection.
+type stmtCache struct {
+	mu      sync.Mutex
+	dealloc func(context.Context, string) error
+	lru     *list.List // of *pgconn.StatementDescription with the most recently used first
+	idx     map[string]*list.Element
+}
+
+func newStmtCache(dealloc func(context.Context, string) error) *stmtCache {
+	return &stmtCache{dealloc: dealloc, lru: list.New(), idx: make(map[string]*list.Element)}
+}
+
+var stmtCaches struct {
+	sync.Mutex
+	m map[*pgx.Conn]*stmtCache
+}
+
+// PruneStmtCaches drops the statement caches of closed connections and returns true.
+// It is installed by Open as pgxpool.Config.AfterRelease hook. Custom pools should use it too,
+// otherwise the caches of closed connections are only dropped when a new connection is used.
+func PruneStmtCaches(*pgx.Conn) bool {
+	stmtCaches.Lock()
+	defer stmtCaches.Unlock()
+	pruneStmtCaches()
+	return true
+}
+
+func pruneStmtCaches() {
+	for k := range stmtCaches.m {
+		if k.IsClosed() {
+			delete(stmtCaches.m, k)
+		}
+	}
+}
+
+// connStmtCache returns the statement cache for the connection used by pc or nil.
+func connStmtCache(pc PC) *stmtCache {
+	var conn *pgx.Conn
+	switch c := pc.(type) {
+	case *pgx.Conn:
+		conn = c
+	case interface{ Conn() *pgx.Conn }:
+		conn = c.Conn()
+	}
+	if conn == nil {
+		return nil
+	}
+	stmtCaches.Lock()
+	defer stmtCaches.Unlock()
+	c := stmtCaches.m[conn]
+	if c == nil {
+		if stmtCaches.m == nil {
+			stmtCaches.m = make(map[*pgx.Conn]*stmtCache)
+		}
+		pruneStmtCaches()
+		c = newStmtCache(conn.Deallocate)
+		stmtCaches.m[conn] = c
+	}
+	return c
+}
+
+func (c *stmtCache) prepare(ctx context.Context, pc PC, name, sql string) (*pgconn.StatementDescription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el := c.idx[name]; el != nil {
+		c.lru.MoveToFront(el)
+		return el.Value.(*pgconn.StatementDescription), nil
+	}
+	sd, err := pc.Prepare(ctx, name, sql)
+	if isPgCode(err, "42P05") {
+		// the statement was not deallocated because the transaction was aborted
+		c.dealloc(ctx, name)
+		sd, err = pc.Prepare(ctx, name, sql)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.idx[name] = c.lru.PushFront(sd)
+	for c.lru.Len() > StmtCacheSize && StmtCacheSize > 0 {
+		c.remove(ctx, c.lru.Back())
+	}
+	return sd, nil
+}
+
+func (c *stmtCache) invalidate(ctx context.Context, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el := c.idx[name]; el != nil {
+		c.remove(ctx, el)
+	} else {
+		c.dealloc(ctx, name)
+	}
+}
+
+func (c *stmtCache) remove(ctx context.Context, el *list.Element) {
+	sd := c.lru.Remove(el).(*pgconn.StatementDescription)
+	delete(c.idx, sd.Name)
+	// we ignore errors, a statement that is still allocated is handled when prepared again
+	c.dealloc(ctx, sd.Name)
+}
+
+// prepare returns the statement description for sql using the cache of the connection.
+func prepare(ctx context.Context, pc PC, sql string) (*pgconn.StatementDescription, error) {
+	name := hashSql(sql)
+	if c := connStmtCache(pc); c != nil {
+		return c.prepare(ctx, pc, name, sql)
+	}
+	return pc.Prepare(ctx, name, sql)
+}
+
+// invalidate removes the prepared statement for sql so that it is prepared again on next use.
+func invalidate(ctx context.Context, pc PC, sql string) {
+	if c := connStmtCache(pc); c != nil {
+		c.invalidate(ctx, hashSql(sql))
+	}
+}
+
+// isStalePlan returns whether err reports that a prepared statement was invalidated by ddl.
+func isStalePlan(err error) bool {
+	var pe *pgconn.PgError
+	return errors.As(err, &pe) && pe.Code == "0A000" &&
+		strings.Contains(pe.Message, "cached plan must not change result type")
+}
+
+func isPgCode(err error, code string) bool {
+	var pe *pgconn.PgError
+	return errors.As(err, &pe) && pe.Code == code
+}
+
+// canRetry returns whether a failed statement can be retried on pc. Errors abort transactions.
+func canRetry(pc PC) bool {
+	_, tx := pc.(pgx.Tx)
+	return !tx
+}
+
+// stmtRows invalidates stale prepared statements and retries the query once, if the error is
+// reported before the first row.
+type stmtRows struct {
+	pgx.Rows
+	ctx  context.Context
+	pc   PC
+	sql  string
+	args []lit.Val
+	read bool
+	err  error
+}
+
+func (r *stmtRows) Next() bool {
+	if r.Rows.Next() {
+		r.read = true
+		return true
+	}
+	if err := r.Rows.Err(); isStalePlan(err) {
+		invalidate(r.ctx, r.pc, r.sql)
+		if !r.read && canRetry(r.pc) {
+			r.read = true
+			rows, err := query(r.ctx, r.pc, r.sql, r.args)
+			if err != nil {
+				r.err = err
+				return false
+			}
+			r.Rows = rows
+			return r.Next()
+		}
+	}
+	return false
+}
+
+func (r *stmtRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
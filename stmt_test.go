@@ -0,0 +1,127 @@
+package dapgx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	pgx "github.com/jackc/pgx/v4"
+)
+
+type fakePC struct {
+	PC
+	prepared []string
+	dupe     map[string]bool
+	rows     []*fakeRows
+	tag      pgconn.CommandTag
+	err      error
+}
+
+func (pc *fakePC) Prepare(_ context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if pc.dupe[name] {
+		delete(pc.dupe, name)
+		return nil, &pgconn.PgError{Code: "42P05"}
+	}
+	pc.prepared = append(pc.prepared, name)
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+func (pc *fakePC) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	r := pc.rows[0]
+	pc.rows = pc.rows[1:]
+	return r, nil
+}
+
+func (pc *fakePC) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pc.tag, pc.err
+}
+
+type fakeRows struct {
+	pgx.Rows
+	n   int
+	err error
+	tag pgconn.CommandTag
+}
+
+func (r *fakeRows) Next() bool {
+	if r.n > 0 {
+		r.n--
+		return true
+	}
+	return false
+}
+func (r *fakeRows) Err() error                    { return r.err }
+func (r *fakeRows) Close()                        {}
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return r.tag }
+
+type deallocs []string
+
+func (d *deallocs) dealloc(_ context.Context, name string) error {
+	*d = append(*d, name)
+	return nil
+}
+
+func TestStmtCacheEvict(t *testing.T) {
+	defer func(size int) { StmtCacheSize = size }(StmtCacheSize)
+	StmtCacheSize = 2
+	ctx := context.Background()
+	pc := &fakePC{}
+	var d deallocs
+	c := newStmtCache(d.dealloc)
+	for _, name := range []string{"a", "b", "a", "c", "b"} {
+		_, err := c.prepare(ctx, pc, name, "select "+name)
+		if err != nil {
+			t.Fatalf("prepare %s: %v", name, err)
+		}
+	}
+	// a is used before c, so b is evicted first and then a when b is prepared again
+	if got := strings.Join(d, " "); got != "b a" {
+		t.Errorf("want deallocated b a got %s", got)
+	}
+	if got := strings.Join(pc.prepared, " "); got != "a b c b" {
+		t.Errorf("want prepared a b c b got %s", got)
+	}
+	if c.lru.Len() != 2 || c.idx["a"] != nil {
+		t.Errorf("want cached c b got %d entries", c.lru.Len())
+	}
+}
+
+func TestStmtCacheDuplicate(t *testing.T) {
+	ctx := context.Background()
+	pc := &fakePC{dupe: map[string]bool{"a": true}}
+	var d deallocs
+	c := newStmtCache(d.dealloc)
+	sd, err := c.prepare(ctx, pc, "a", "select 1")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if sd.Name != "a" || len(d) != 1 || d[0] != "a" {
+		t.Errorf("want a deallocated and prepared again got %s %v", sd.Name, d)
+	}
+}
+
+func TestStmtRowsRetry(t *testing.T) {
+	stale := &pgconn.PgError{Code: "0A000", Message: "cached plan must not change result type"}
+	ctx := context.Background()
+	pc := &fakePC{rows: []*fakeRows{{n: 1}}}
+	r := &stmtRows{Rows: &fakeRows{err: stale}, ctx: ctx, pc: pc, sql: "select 1"}
+	if !r.Next() || r.Next() || r.Err() != nil {
+		t.Fatalf("want retry with one row got err %v", r.Err())
+	}
+	if len(pc.prepared) != 1 {
+		t.Errorf("want prepared once got %v", pc.prepared)
+	}
+	// a stale plan after the first row is returned as is
+	pc = &fakePC{rows: []*fakeRows{{n: 1}}}
+	r = &stmtRows{Rows: &fakeRows{n: 1, err: stale}, ctx: ctx, pc: pc, sql: "select 1"}
+	if !r.Next() || r.Next() || r.Err() != stale {
+		t.Errorf("want stale plan error after first row got %v", r.Err())
+	}
+	// and only retried once
+	pc = &fakePC{rows: []*fakeRows{{err: stale}}}
+	r = &stmtRows{Rows: &fakeRows{err: stale}, ctx: ctx, pc: pc, sql: "select 1"}
+	if r.Next() || r.Err() != stale || len(pc.prepared) != 1 {
+		t.Errorf("want stale plan error after one retry got %v", r.Err())
+	}
+}
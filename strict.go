@@ -0,0 +1,213 @@
+package dapgx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgtype"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/typ"
+)
+
+// MismatchError reports all differences between the result columns and the target type found by a
+// strict scanner.
+type MismatchError struct {
+	Type typ.Type
+	Errs []string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("columns do not match %s: %s", e.Type, strings.Join(e.Errs, "; "))
+}
+
+// NewStrictScanner returns a scanner for rows like NewScanner, but validates the result columns
+// against the target type t once. Object types expect a column for each field, with embedded
+// fields flattened, and no other columns. Dotted column names select nested fields. The column
+// types must be compatible with the field types. Unknown types, like enums without types
+// registry, are expected to be decoded as text. All mismatches are reported together as
+// *MismatchError.
+func NewStrictScanner(t typ.Type, scal bool, rows pgx.Rows) (*Scanner, error) {
+	s, err := NewScanner(scal, rows)
+	if err != nil {
+		return nil, err
+	}
+	ts := RowsTypes(rows)
+	fds := rows.FieldDescriptions()
+	var errs []string
+	if scal {
+		if fd := fds[0]; !oidMatches(ts, fd.DataTypeOID, t) {
+			errs = append(errs, colMismatch(string(fd.Name), fd.DataTypeOID, t))
+		}
+	} else {
+		ps, err := colParams(nil, t)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(fds))
+		for _, fd := range fds {
+			name := string(fd.Name)
+			key, rest := name, ""
+			if idx := strings.IndexByte(name, '.'); idx > 0 {
+				key, rest = name[:idx], name[idx+1:]
+			}
+			seen[key] = true
+			p, ok := ps.m[key]
+			pt := p.Type
+			if ok && rest != "" {
+				pt, ok = pathType(pt, rest)
+			}
+			if !ok {
+				errs = append(errs, fmt.Sprintf("unexpected column %s", name))
+			} else if !oidMatches(ts, fd.DataTypeOID, pt) {
+				errs = append(errs, colMismatch(name, fd.DataTypeOID, pt))
+			}
+		}
+		for _, p := range ps.sorted {
+			if !seen[p.Key] {
+				errs = append(errs, fmt.Sprintf("missing column %s", p.Key))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &MismatchError{t, errs}
+	}
+	return s, nil
+}
+
+func colMismatch(name string, oid uint32, t typ.Type) string {
+	return fmt.Sprintf("column %s with type oid %d is incompatible with %s", name, oid, t)
+}
+
+type colMap struct {
+	m      map[string]typ.Param
+	sorted []typ.Param
+}
+
+// colParams returns the fields of the object type t with embedded fields flattened.
+func colParams(res *colMap, t typ.Type) (*colMap, error) {
+	if res == nil {
+		res = &colMap{m: make(map[string]typ.Param)}
+	}
+	pb, ok := typ.Deopt(t).Body.(*typ.ParamBody)
+	if !ok {
+		return nil, fmt.Errorf("strict scan expects object type got %s", t)
+	}
+	for _, p := range pb.Params {
+		if p.Key == "" {
+			if _, err := colParams(res, p.Type); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		res.m[p.Key] = p
+		res.sorted = append(res.sorted, p)
+	}
+	return res, nil
+}
+
+// pathType returns the type of the nested field at the dotted path in object type t.
+func pathType(t typ.Type, path string) (typ.Type, bool) {
+	for _, key := range strings.Split(path, ".") {
+		pb, ok := typ.Deopt(t).Body.(*typ.ParamBody)
+		if !ok {
+			return t, false
+		}
+		found := false
+		for _, p := range pb.Params {
+			if p.Key == key {
+				t, found = p.Type, true
+				break
+			}
+		}
+		if !found {
+			return t, false
+		}
+	}
+	return t, true
+}
+
+// oidMatches returns whether values of the postgres type oid can be assigned to type t.
+func oidMatches(ts *Types, oid uint32, t typ.Type) bool {
+	t = typ.Deopt(t)
+	if ts.codec(oid) != nil {
+		// we cannot know what custom codecs return
+		return true
+	}
+	var mask knd.Kind
+	switch ti := ts.Info(oid); {
+	case ti != nil && ti.Cat == 'A':
+		return t.Kind&(knd.List|knd.Idxr) != 0 && oidMatches(ts, ti.Elem, typ.ContEl(t))
+	case ti != nil && (ti.Kind == 'c' || ti.Kind == 'r' || ti.Kind == 'm'):
+		mask = knd.Obj | knd.Keyr | knd.List | knd.Idxr
+	}
+	if el, ok := arrayElems[oid]; ok {
+		return t.Kind&(knd.List|knd.Idxr) != 0 && oidMatches(ts, el, typ.ContEl(t))
+	}
+	if mask == 0 {
+		switch oid = ts.Resolve(oid); oid {
+		case pgtype.BoolOID:
+			mask = knd.Bool
+		case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID, pgtype.BitOID, pgtype.VarbitOID,
+			pgtype.Float4OID, pgtype.Float8OID, pgtype.NumericOID:
+			mask = knd.Num | knd.Int | knd.Real | knd.Bits
+		case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.QCharOID,
+			pgtype.InetOID, pgtype.CIDROID, pgtype.MacaddrOID, 774 /*Macaddr8OID*/ :
+			mask = knd.Char | knd.Str | knd.Enum
+		case pgtype.ByteaOID:
+			mask = knd.Raw
+		case pgtype.UUIDOID:
+			mask = knd.UUID
+		case pgtype.DateOID, pgtype.TimestampOID, pgtype.TimestamptzOID:
+			mask = knd.Time
+		case pgtype.TimeOID, pgtype.IntervalOID:
+			mask = knd.Span
+		case pgtype.JSONOID, pgtype.JSONBOID:
+			return true
+		case pgtype.RecordOID:
+			mask = knd.Obj | knd.Keyr
+		default:
+			if _, ok := rangeElems[oid]; ok {
+				mask = knd.Obj | knd.Keyr | knd.List | knd.Idxr
+			} else if _, ok := rangeArrays[oid]; ok {
+				mask = knd.List | knd.Idxr
+			} else {
+				// unknown types like enums without registry are decoded as text
+				mask = knd.Char | knd.Str | knd.Enum
+			}
+		}
+	}
+	return t.Kind&mask != 0
+}
+
+// arrayElems maps the built-in array types to their element types.
+var arrayElems = map[uint32]uint32{
+	pgtype.BoolArrayOID:        pgtype.BoolOID,
+	pgtype.ByteaArrayOID:       pgtype.ByteaOID,
+	pgtype.Int2ArrayOID:        pgtype.Int2OID,
+	pgtype.Int4ArrayOID:        pgtype.Int4OID,
+	pgtype.Int8ArrayOID:        pgtype.Int8OID,
+	pgtype.Float4ArrayOID:      pgtype.Float4OID,
+	pgtype.Float8ArrayOID:      pgtype.Float8OID,
+	pgtype.NumericArrayOID:     pgtype.NumericOID,
+	pgtype.TextArrayOID:        pgtype.TextOID,
+	pgtype.VarcharArrayOID:     pgtype.TextOID,
+	pgtype.BPCharArrayOID:      pgtype.BPCharOID,
+	1002 /*QCharArrayOID*/ :    pgtype.QCharOID,
+	1003 /*NameArrayOID*/ :     pgtype.NameOID,
+	1028 /*OIDArrayOID*/ :      pgtype.OIDOID,
+	pgtype.InetArrayOID:        pgtype.InetOID,
+	pgtype.CIDRArrayOID:        pgtype.CIDROID,
+	1040 /*MacaddrArrayOID*/ :  pgtype.MacaddrOID,
+	775 /*Macaddr8ArrayOID*/ :  774, /*Macaddr8OID*/
+	1561 /*BitArrayOID*/ :      pgtype.BitOID,
+	1563 /*VarbitArrayOID*/ :   pgtype.VarbitOID,
+	pgtype.UUIDArrayOID:        pgtype.UUIDOID,
+	pgtype.DateArrayOID:        pgtype.DateOID,
+	pgtype.TimestampArrayOID:   pgtype.TimestampOID,
+	pgtype.TimestamptzArrayOID: pgtype.TimestamptzOID,
+	1183 /*TimeArrayOID*/ :     pgtype.TimeOID,
+	1187 /*IntervalArrayOID*/ : pgtype.IntervalOID,
+	199 /* JSONArrayOID*/ :     pgtype.JSONOID,
+	pgtype.JSONBArrayOID:       pgtype.JSONBOID,
+}
@@ -0,0 +1,178 @@
+package dapgx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// InfPolicy decides how infinite dates and timestamps are decoded.
+type InfPolicy uint8
+
+const (
+	InfZero     InfPolicy = iota // decode as zero time, the default
+	InfSentinel                  // decode as InfTime or NegInfTime
+	InfError                     // return an error
+	InfNull                      // decode as null
+)
+
+// InfTime and NegInfTime are the sentinels for infinity and -infinity. Both are encoded as
+// infinite values regardless of the policy.
+var (
+	InfTime    = time.Date(294277, 1, 1, 0, 0, 0, 0, time.UTC)
+	NegInfTime = time.Date(-4713, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// TimeOpts configure the date and timestamp codecs. Attach them to a context with WithTimeOpts
+// to use them for all queries with that context, or set them for a single scanner.
+type TimeOpts struct {
+	// Loc is the location of decoded times and dates. Dates are encoded as the date in Loc and
+	// timestamps without time zone as the wall time in Loc. Nil means time.Local, but with
+	// timestamps without time zone stored in UTC.
+	Loc *time.Location
+	// Inf is the policy for infinite dates and timestamps.
+	Inf InfPolicy
+}
+
+var defaultTimeOpts = &TimeOpts{}
+
+func (o *TimeOpts) loc() *time.Location {
+	if o == nil || o.Loc == nil {
+		return time.Local
+	}
+	return o.Loc
+}
+
+// stamp returns the timestamp without time zone t, that was read as UTC, as wall time in the
+// options location.
+func (o *TimeOpts) stamp(t time.Time) time.Time {
+	if o == nil || o.Loc == nil {
+		return t.In(time.Local)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), o.Loc)
+}
+
+func (o *TimeOpts) inf(neg bool) (lit.Val, error) {
+	if o == nil {
+		return lit.Time{}, nil
+	}
+	switch o.Inf {
+	case InfSentinel:
+		if neg {
+			return lit.Time(NegInfTime), nil
+		}
+		return lit.Time(InfTime), nil
+	case InfError:
+		return nil, fmt.Errorf("unexpected infinite time value")
+	case InfNull:
+		return lit.Null{}, nil
+	}
+	return lit.Time{}, nil
+}
+
+// decs returns the decoders for the date and timestamp type oid and their arrays or nil.
+func (o *TimeOpts) decs(oid uint32) DecoderPair {
+	switch oid {
+	case pgtype.DateOID:
+		return DecoderPair{o.dateTextDec, o.dateBinDec}
+	case pgtype.TimestampOID:
+		return DecoderPair{o.tsTextDec, o.tsBinDec}
+	case pgtype.TimestamptzOID:
+		return DecoderPair{o.tstzTextDec, o.tstzBinDec}
+	case pgtype.DateArrayOID, pgtype.TimestampArrayOID, pgtype.TimestamptzArrayOID:
+		el := o.decs(arrayElems[oid])
+		return arrayDecs(el.Text, el.Binary, typ.Time)
+	}
+	return DecoderPair{}
+}
+
+// FieldDecoder returns a decoder for oid like the package function, but uses the options for
+// date and timestamp types.
+func (o *TimeOpts) FieldDecoder(oid uint32, bin bool) Decoder {
+	decs := o.decs(oid)
+	if decs.Text == nil {
+		return FieldDecoder(oid, bin)
+	}
+	if bin {
+		return decs.Binary
+	}
+	return decs.Text
+}
+
+// FieldEncoder returns an encoder for oid like the package function, but encodes dates and
+// timestamps without time zone as the date or wall time in the options location.
+func (o *TimeOpts) FieldEncoder(oid uint32, arg lit.Val) (Encoder, error) {
+	return FieldEncoder(oid, o.convert(oid, arg))
+}
+
+// convert returns arg with dates converted to the options location and timestamps without time
+// zone to a UTC time with the wall time in that location, because they are encoded in UTC.
+func (o *TimeOpts) convert(oid uint32, arg lit.Val) lit.Val {
+	if o == nil || o.Loc == nil || arg == nil || arg.Nil() {
+		return arg
+	}
+	switch oid {
+	case pgtype.DateOID:
+		if t, err := lit.ToTime(arg); err == nil {
+			return lit.Time(time.Time(t).In(o.Loc))
+		}
+	case pgtype.TimestampOID:
+		t, err := lit.ToTime(arg)
+		if err != nil || infSign(time.Time(t)) != 0 {
+			break
+		}
+		w := time.Time(t).In(o.Loc)
+		return lit.Time(time.Date(w.Year(), w.Month(), w.Day(),
+			w.Hour(), w.Minute(), w.Second(), w.Nanosecond(), time.UTC))
+	case pgtype.DateArrayOID, pgtype.TimestampArrayOID:
+		idxr, ok := lit.Unwrap(arg).(lit.Idxr)
+		if !ok {
+			break
+		}
+		vals := make([]lit.Val, 0, idxr.Len())
+		idxr.IterIdx(func(_ int, v lit.Val) error {
+			vals = append(vals, o.convert(arrayElems[oid], v))
+			return nil
+		})
+		return lit.NewList(typ.Time, vals...)
+	}
+	return arg
+}
+
+type timeOptsKey struct{}
+
+// WithTimeOpts returns a new context with the time options o attached.
+func WithTimeOpts(ctx context.Context, o *TimeOpts) context.Context {
+	return context.WithValue(ctx, timeOptsKey{}, o)
+}
+
+// TimeOptsFrom returns the time options attached to ctx or nil.
+func TimeOptsFrom(ctx context.Context) *TimeOpts {
+	o, _ := ctx.Value(timeOptsKey{}).(*TimeOpts)
+	return o
+}
+
+// RowsTimeOpts returns the time options attached to rows or nil.
+func RowsTimeOpts(rows pgx.Rows) *TimeOpts {
+	if tr, ok := rows.(interface{ TimeOpts() *TimeOpts }); ok {
+		return tr.TimeOpts()
+	}
+	return nil
+}
+
+// infSign returns 1 for InfTime, -1 for NegInfTime and otherwise 0.
+func infSign(t time.Time) int {
+	switch {
+	case !t.Before(InfTime):
+		return 1
+	case !t.After(NegInfTime):
+		return -1
+	}
+	return 0
+}
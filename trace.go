@@ -0,0 +1,141 @@
+package dapgx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/lit"
+)
+
+// Tracer is notified about all statements executed by dapgx and its sub packages. It can be used
+// for structured logging, slow query reports or metrics.
+type Tracer interface {
+	// TraceStart is called before the statement is executed and may return a new context.
+	TraceStart(ctx context.Context, t *Trace) context.Context
+	// TraceEnd is called after the statement is done, with duration, rows and error set.
+	TraceEnd(ctx context.Context, t *Trace)
+}
+
+// Trace holds the details of one traced statement.
+type Trace struct {
+	SQL   string
+	Args  []lit.Val // nil for statements with raw or no arguments
+	Start time.Time
+	Dur   time.Duration
+	Rows  int64 // rows affected, returned or copied
+	Err   error
+}
+
+var tracer struct {
+	sync.RWMutex
+	t Tracer
+}
+
+// SetTracer sets the tracer used for all statements. Passing nil disables tracing.
+func SetTracer(t Tracer) {
+	tracer.Lock()
+	tracer.t = t
+	tracer.Unlock()
+}
+
+// StartTrace starts tracing the statement sql with args and returns a context and an end
+// function, that must be called once with the row count and error of the statement.
+func StartTrace(ctx context.Context, sql string, args []lit.Val) (context.Context, func(int64, error)) {
+	tracer.RLock()
+	t := tracer.t
+	tracer.RUnlock()
+	if t == nil {
+		return ctx, func(int64, error) {}
+	}
+	tr := &Trace{SQL: sql, Args: args, Start: time.Now()}
+	ctx = t.TraceStart(ctx, tr)
+	return ctx, func(rows int64, err error) {
+		tr.Dur = time.Since(tr.Start)
+		tr.Rows, tr.Err = rows, err
+		t.TraceEnd(ctx, tr)
+	}
+}
+
+// TraceExec executes sql with raw pgx args using c and traces the statement.
+func TraceExec(ctx context.Context, c C, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, end := StartTrace(ctx, sql, nil)
+	tag, err := c.Exec(ctx, sql, args...)
+	end(tag.RowsAffected(), err)
+	return tag, err
+}
+
+// TraceQuery queries sql with raw pgx args using c and traces the statement until the returned
+// rows are read or closed.
+func TraceQuery(ctx context.Context, c C, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, end := StartTrace(ctx, sql, nil)
+	rows, err := c.Query(ctx, sql, args...)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	return TraceRows(rows, end), nil
+}
+
+// TraceRows returns rows that call end from StartTrace when the rows are read or closed.
+func TraceRows(rows pgx.Rows, end func(int64, error)) pgx.Rows {
+	return &traceRows{Rows: rows, end: end}
+}
+
+// traceRows ends the trace of a query when the rows are read or closed.
+type traceRows struct {
+	pgx.Rows
+	end  func(int64, error)
+	done bool
+}
+
+func (r *traceRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+func (r *traceRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *traceRows) finish() {
+	if !r.done {
+		r.done = true
+		r.end(r.Rows.CommandTag().RowsAffected(), r.Rows.Err())
+	}
+}
+
+// LogTracer is a tracer that logs statements to a pgx logger. Errors are logged with level error,
+// statements slower than Slow with level warn and all others with level Level.
+type LogTracer struct {
+	Logger pgx.Logger
+	Level  pgx.LogLevel
+	Slow   time.Duration
+}
+
+func (lt LogTracer) TraceStart(ctx context.Context, t *Trace) context.Context { return ctx }
+func (lt LogTracer) TraceEnd(ctx context.Context, t *Trace) {
+	lvl, msg := lt.Level, "query"
+	if t.Err != nil {
+		lvl, msg = pgx.LogLevelError, "query failed"
+	} else if lt.Slow > 0 && t.Dur >= lt.Slow {
+		lvl, msg = pgx.LogLevelWarn, "slow query"
+	}
+	if lvl == pgx.LogLevelNone {
+		return
+	}
+	data := map[string]interface{}{"sql": t.SQL, "time": t.Dur, "rows": t.Rows}
+	if len(t.Args) > 0 {
+		data["args"] = t.Args
+	}
+	if t.Err != nil {
+		data["err"] = t.Err
+	}
+	lt.Logger.Log(ctx, lvl, msg, data)
+}
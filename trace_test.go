@@ -0,0 +1,78 @@
+package dapgx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"xelf.org/xelf/lit"
+)
+
+type recTracer struct {
+	started []string
+	ended   []*Trace
+}
+
+func (r *recTracer) TraceStart(ctx context.Context, t *Trace) context.Context {
+	r.started = append(r.started, t.SQL)
+	return ctx
+}
+func (r *recTracer) TraceEnd(ctx context.Context, t *Trace) { r.ended = append(r.ended, t) }
+
+func TestTracer(t *testing.T) {
+	rec := &recTracer{}
+	SetTracer(rec)
+	defer SetTracer(nil)
+	ctx := context.Background()
+	errFail := errors.New("failed")
+	pc := &fakePC{tag: pgconn.CommandTag("UPDATE 3"), rows: []*fakeRows{
+		{n: 2, tag: pgconn.CommandTag("SELECT 2")},
+		{n: 1, tag: pgconn.CommandTag("SELECT 1")},
+	}}
+	_, err := TraceExec(ctx, pc, "update a")
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	rows, err := TraceQuery(ctx, pc, "select a")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(rec.ended) != 1 {
+		t.Errorf("want query trace to end after reading rows got %d ended", len(rec.ended))
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	rows, err = Query(ctx, pc, "select b", nil)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	// closing the rows early ends the trace too
+	rows.Close()
+	pc.tag, pc.err = nil, errFail
+	err = Exec(ctx, pc, "update b", []lit.Val{})
+	if err != errFail {
+		t.Errorf("want exec error got %v", err)
+	}
+	want := []struct {
+		sql  string
+		rows int64
+		err  error
+	}{
+		{"update a", 3, nil},
+		{"select a", 2, nil},
+		{"select b", 1, nil},
+		{"update b", 0, errFail},
+	}
+	if len(rec.started) != len(want) || len(rec.ended) != len(want) {
+		t.Fatalf("want %d traces got %d started %d ended",
+			len(want), len(rec.started), len(rec.ended))
+	}
+	for i, w := range want {
+		tr := rec.ended[i]
+		if rec.started[i] != w.sql || tr.SQL != w.sql || tr.Rows != w.rows || tr.Err != w.err {
+			t.Errorf("want trace %s %d %v got %s %d %v", w.sql, w.rows, w.err, tr.SQL, tr.Rows, tr.Err)
+		}
+	}
+}
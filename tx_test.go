@@ -0,0 +1,139 @@
+package dapgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	pgx "github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var testDsn = "host=/var/run/postgresql dbname=daql"
+
+func testDB(t *testing.T, ddl string) *pgxpool.Pool {
+	db, err := Open(context.Background(), testDsn, nil)
+	if err != nil {
+		t.Fatalf("open db %v", err)
+	}
+	if ddl != "" {
+		_, err = db.Exec(context.Background(), ddl)
+		if err != nil {
+			db.Close()
+			t.Fatalf("setup %v", err)
+		}
+	}
+	return db
+}
+
+func TestWithTxSavepoint(t *testing.T) {
+	db := testDB(t, `drop table if exists dapgx_tx_test;
+		create table dapgx_tx_test (id int primary key)`)
+	defer db.Close()
+	ctx := context.Background()
+	defer db.Exec(ctx, "drop table dapgx_tx_test")
+	errInner := errors.New("inner failed")
+	err := WithTx(ctx, db, func(c PC) error {
+		_, err := c.Exec(ctx, "insert into dapgx_tx_test values (1)")
+		if err != nil {
+			return err
+		}
+		err = WithTx(ctx, c.(DB), func(c PC) error {
+			_, err := c.Exec(ctx, "insert into dapgx_tx_test values (2)")
+			if err != nil {
+				return err
+			}
+			return errInner
+		})
+		if err != errInner {
+			t.Errorf("want inner error got %v", err)
+		}
+		// a failing statement in the savepoint must not abort the outer transaction
+		err = WithTx(ctx, c.(DB), func(c PC) error {
+			_, err := c.Exec(ctx, "insert into dapgx_tx_test values (1)")
+			return err
+		})
+		if err == nil {
+			t.Errorf("want duplicate key error")
+		}
+		_, err = c.Exec(ctx, "insert into dapgx_tx_test values (3)")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("outer tx %v", err)
+	}
+	var ids []int32
+	err = db.QueryRow(ctx, "select array_agg(id order by id) from dapgx_tx_test").Scan(&ids)
+	if err != nil {
+		t.Fatalf("query %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("want ids [1 3] got %v", ids)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	r := Retry{Max: 10, Backoff: 10 * time.Millisecond, Limit: 50 * time.Millisecond}
+	for n, want := range []time.Duration{10, 20, 40, 50, 50, 50} {
+		want *= time.Millisecond
+		// the backoff is jittered between half and the full duration
+		for i := 0; i < 20; i++ {
+			if got := r.backoff(n + 1); got < want/2 || got > want {
+				t.Errorf("attempt %d want backoff in [%s, %s] got %s", n+1, want/2, want, got)
+				break
+			}
+		}
+	}
+	if got := (Retry{Max: 3}).backoff(2); got != 0 {
+		t.Errorf("want no backoff got %s", got)
+	}
+	// shifts past the int64 range are capped by the limit
+	if got := r.backoff(70); got < r.Limit/2 || got > r.Limit {
+		t.Errorf("want overflow capped by limit got %s", got)
+	}
+}
+
+type failTxDB struct {
+	TxDB
+	n   int
+	err error
+}
+
+func (db *failTxDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	db.n++
+	return nil, db.err
+}
+
+func TestRetryMax(t *testing.T) {
+	ser := &pgconn.PgError{Code: "40001"}
+	for _, test := range []struct{ max, want int }{{0, 1}, {1, 1}, {3, 3}} {
+		db := &failTxDB{err: ser}
+		err := WithTxOpts(context.Background(), db, pgx.TxOptions{}, Retry{Max: test.max},
+			func(PC) error { return nil })
+		if err != ser || db.n != test.want {
+			t.Errorf("max %d want %d attempts got %d %v", test.max, test.want, db.n, err)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "40001"}, true},
+		{&pgconn.PgError{Code: "40P01"}, true},
+		{fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{errors.New("40001"), false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if got := IsRetryable(test.err); got != test.want {
+			t.Errorf("%v want %v got %v", test.err, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package dapgx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgtype"
+	pgx "github.com/jackc/pgx/v4"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// Types is a registry of the user defined types of one database. It is built from the pg_type
+// and pg_enum catalogs and usually shared by all connections of a pool. Call Refresh to pick up
+// types created after loading, for example after running a migration.
+//
+// The registry is attached to a context with WithTypes and then used by Query and Exec to
+// encode arguments and by the scanners of the returned rows to decode results.
+type Types struct {
+	mu   sync.RWMutex
+	oids map[uint32]*TypeInfo
+	decs map[uint32]DecoderPair
+	path []string // schemas of the search path in order
+}
+
+// TypeInfo describes a user defined postgres type.
+type TypeInfo struct {
+	Oid    uint32
+	Name   string   // qualified name schema.name
+	Kind   byte     // typtype: b base, c composite, d domain, e enum, r range, m multirange
+	Cat    byte     // typcategory: A array, S string, U user defined …
+	Elem   uint32   // element type of arrays
+	Base   uint32   // base type of domains
+	Rel    uint32   // relation of composite types
+	Labels []string // enum labels in sort order
+	Attrs  []Attr   // attributes of composite types
+}
+
+// LoadTypes returns a new type registry loaded from the catalog using c or an error.
+func LoadTypes(ctx context.Context, c C) (*Types, error) {
+	ts := &Types{}
+	err := ts.Refresh(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Refresh reloads all user defined types from the catalog using c.
+func (ts *Types) Refresh(ctx context.Context, c C) error {
+	oids, err := queryTypes(ctx, c)
+	if err != nil {
+		return fmt.Errorf("load types: %w", err)
+	}
+	path, err := querySearchPath(ctx, c)
+	if err != nil {
+		return fmt.Errorf("load types: %w", err)
+	}
+	ts.mu.Lock()
+	ts.oids, ts.decs, ts.path = oids, nil, path
+	ts.mu.Unlock()
+	return nil
+}
+
+// Info returns the type info for oid or nil.
+func (ts *Types) Info(oid uint32) *TypeInfo {
+	if ts == nil {
+		return nil
+	}
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.oids[oid]
+}
+
+// Oid returns the oid for a qualified type name like 'public.ltree' or zero. Unqualified names
+// are looked up in the schemas of the search path in order, like postgres does, and otherwise
+// match a type in any schema. Names that match types in more than one other schema return zero.
+func (ts *Types) Oid(name string) uint32 {
+	if ts == nil {
+		return 0
+	}
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	if strings.IndexByte(name, '.') >= 0 {
+		return ts.find(name)
+	}
+	for _, s := range ts.path {
+		if oid := ts.find(s + "." + name); oid != 0 {
+			return oid
+		}
+	}
+	var res uint32
+	for oid, ti := range ts.oids {
+		if ti.Name[strings.LastIndexByte(ti.Name, '.')+1:] == name {
+			if res != 0 {
+				return 0
+			}
+			res = oid
+		}
+	}
+	return res
+}
+
+func (ts *Types) find(qual string) uint32 {
+	for oid, ti := range ts.oids {
+		if ti.Name == qual {
+			return oid
+		}
+	}
+	return 0
+}
+
+// Resolve returns the oid whose codec is used for oid. Domains resolve to their base type and
+// enums and string-like base types like citext to text. Unknown oids are returned as is.
+func (ts *Types) Resolve(oid uint32) uint32 {
+	for ti := ts.Info(oid); ti != nil; ti = ts.Info(oid) {
+		switch {
+		case ti.Kind == 'd':
+			oid = ti.Base
+		case ti.Kind == 'e', ti.Kind == 'b' && ti.Cat == 'S':
+			return pgtype.TextOID
+		default:
+			return oid
+		}
+	}
+	return oid
+}
+
+// FieldDecoder returns a decoder for oid like the package function, but resolves user defined
+// types using the registry. It is safe to call on a nil registry.
+func (ts *Types) FieldDecoder(oid uint32, bin bool) Decoder {
+	decs, ok := ts.decoders(oid)
+	if !ok {
+		return FieldDecoder(oid, bin)
+	}
+	if bin {
+		return decs.Binary
+	}
+	return decs.Text
+}
+
+// FieldEncoder returns an encoder for oid like the package function, but resolves user defined
+// types using the registry. It is safe to call on a nil registry.
+func (ts *Types) FieldEncoder(oid uint32, arg lit.Val) (Encoder, error) {
+	if arg == nil || arg.Nil() {
+		return WrapNull{}, nil
+	}
+	if c := ts.codec(oid); c != nil && c.Encoder != nil {
+		return c.Encoder(oid, arg)
+	}
+	if ti := ts.Info(oid); ti != nil && ti.Cat == 'A' && ts.Info(ti.Elem) != nil {
+		// arrays of user defined types must use the element oid in the array header
+		idxr, ok := lit.Unwrap(arg).(lit.Idxr)
+		if !ok {
+			return nil, fmt.Errorf("no array encoder for %T", arg)
+		}
+		return wrapTypesIdxr{WrapIdxr{idxr, int32(ti.Elem)}, ts}, nil
+	} else if ti != nil && ti.Kind == 'c' {
+		keyr, ok := lit.Unwrap(arg).(lit.Keyr)
+		if !ok {
+			return nil, fmt.Errorf("expect composite object got %T", arg)
+		}
+		return WrapComposite{keyr, ti.Attrs, ts}, nil
+	}
+	return FieldEncoder(ts.Resolve(oid), arg)
+}
+
+// codec returns the custom codec registered for oid or its type name.
+func (ts *Types) codec(oid uint32) *Codec {
+	if c := LookupCodec(oid); c != nil {
+		return c
+	}
+	if ti := ts.Info(oid); ti != nil {
+		return LookupTypeCodec(ti.Name)
+	}
+	return nil
+}
+
+func (ts *Types) decoders(oid uint32) (DecoderPair, bool) {
+	if decs, ok := lookupDecs(oid); ok {
+		return decs, true
+	}
+	if ts == nil {
+		return DecoderPair{}, false
+	}
+	ts.mu.RLock()
+	decs, ok := ts.decs[oid]
+	ts.mu.RUnlock()
+	if ok {
+		return decs, true
+	}
+	ti := ts.Info(oid)
+	if ti == nil {
+		return DecoderPair{}, false
+	}
+	if c := LookupTypeCodec(ti.Name); c != nil && (c.Text != nil || c.Binary != nil) {
+		decs = c.decoders()
+	} else if ti.Cat == 'A' && ti.Elem != 0 {
+		el, ok := ts.decoders(ti.Elem)
+		if !ok {
+			return DecoderPair{}, false
+		}
+		decs = arrayDecs(el.Text, el.Binary, ts.elemType(ti.Elem))
+	} else if ti.Kind == 'c' {
+		decs = compositeDecs(ts, ti.Attrs)
+	} else if res := ts.Resolve(oid); res != oid {
+		decs, ok = ts.decoders(res)
+		if !ok {
+			return DecoderPair{}, false
+		}
+	} else {
+		return DecoderPair{}, false
+	}
+	ts.mu.Lock()
+	if ts.decs == nil {
+		ts.decs = make(map[uint32]DecoderPair)
+	}
+	ts.decs[oid] = decs
+	ts.mu.Unlock()
+	return decs, true
+}
+
+// elemType returns the xelf type of decoded array elements for oid.
+func (ts *Types) elemType(oid uint32) typ.Type {
+	switch ts.Resolve(oid) {
+	case pgtype.BoolOID:
+		return typ.Bool
+	case pgtype.ByteaOID:
+		return typ.Raw
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID:
+		return typ.Int
+	case pgtype.Float4OID, pgtype.Float8OID:
+		return typ.Real
+	case pgtype.NumericOID:
+		return typ.Num
+	case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.QCharOID,
+		pgtype.InetOID, pgtype.CIDROID, pgtype.MacaddrOID:
+		return typ.Str
+	case pgtype.BitOID, pgtype.VarbitOID:
+		return typ.Type{Kind: knd.Bits}
+	case pgtype.UUIDOID:
+		return typ.UUID
+	case pgtype.DateOID, pgtype.TimestampOID, pgtype.TimestamptzOID:
+		return typ.Time
+	case pgtype.TimeOID, pgtype.IntervalOID:
+		return typ.Span
+	}
+	return typ.Data
+}
+
+const typesQuery = `SELECT t.oid::int8, n.nspname, t.typname, t.typtype::text, t.typcategory::text,
+	t.typelem::int8, t.typbasetype::int8, t.typrelid::int8
+	FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+	WHERE t.oid >= 16384`
+
+const attrsQuery = `SELECT t.oid::int8, a.attname, a.atttypid::int8
+	FROM pg_type t JOIN pg_attribute a ON a.attrelid = t.typrelid
+	WHERE t.oid >= 16384 AND t.typtype = 'c' AND a.attnum > 0 AND NOT a.attisdropped
+	ORDER BY t.oid, a.attnum`
+
+const enumsQuery = `SELECT enumtypid::int8, enumlabel FROM pg_enum
+	WHERE enumtypid >= 16384 ORDER BY enumtypid, enumsortorder`
+
+func querySearchPath(ctx context.Context, c C) ([]string, error) {
+	rows, err := TraceQuery(ctx, c, `SELECT unnest(current_schemas(true))::text`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []string
+	for rows.Next() {
+		var s string
+		if err = rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+func queryTypes(ctx context.Context, c C) (map[uint32]*TypeInfo, error) {
+	rows, err := TraceQuery(ctx, c, typesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make(map[uint32]*TypeInfo)
+	for rows.Next() {
+		var oid, elem, base, rel int64
+		var schema, name, kind, cat string
+		err = rows.Scan(&oid, &schema, &name, &kind, &cat, &elem, &base, &rel)
+		if err != nil {
+			return nil, err
+		}
+		res[uint32(oid)] = &TypeInfo{
+			Oid:  uint32(oid),
+			Name: schema + "." + name,
+			Kind: firstByte(kind),
+			Cat:  firstByte(cat),
+			Elem: uint32(elem),
+			Base: uint32(base),
+			Rel:  uint32(rel),
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = TraceQuery(ctx, c, enumsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var oid int64
+		var label string
+		err = rows.Scan(&oid, &label)
+		if err != nil {
+			return nil, err
+		}
+		if ti := res[uint32(oid)]; ti != nil {
+			ti.Labels = append(ti.Labels, label)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = TraceQuery(ctx, c, attrsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var oid, aoid int64
+		var name string
+		err = rows.Scan(&oid, &name, &aoid)
+		if err != nil {
+			return nil, err
+		}
+		if ti := res[uint32(oid)]; ti != nil {
+			ti.Attrs = append(ti.Attrs, Attr{Name: name, Oid: uint32(aoid)})
+		}
+	}
+	return res, rows.Err()
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+type typesKey struct{}
+
+// WithTypes returns a context with the type registry ts attached.
+func WithTypes(ctx context.Context, ts *Types) context.Context {
+	return context.WithValue(ctx, typesKey{}, ts)
+}
+
+// TypesFrom returns the type registry attached to ctx or nil.
+func TypesFrom(ctx context.Context) *Types {
+	ts, _ := ctx.Value(typesKey{}).(*Types)
+	return ts
+}
+
+// typesRows carries the type registry and time options to scanners of the rows.
+type typesRows struct {
+	pgx.Rows
+	types *Types
+	time  *TimeOpts
+}
+
+func (r typesRows) Types() *Types       { return r.types }
+func (r typesRows) TimeOpts() *TimeOpts { return r.time }
+
+// withTypes returns rows with the type registry and time options from ctx if any.
+func withTypes(ctx context.Context, rows pgx.Rows) pgx.Rows {
+	ts, to := TypesFrom(ctx), TimeOptsFrom(ctx)
+	if ts == nil && to == nil {
+		return rows
+	}
+	return typesRows{rows, ts, to}
+}
+
+// RowsTypes returns the type registry attached to rows or nil.
+func RowsTypes(rows pgx.Rows) *Types {
+	if tr, ok := rows.(interface{ Types() *Types }); ok {
+		return tr.Types()
+	}
+	return nil
+}
+
+type wrapTypesIdxr struct {
+	WrapIdxr
+	ts *Types
+}
+
+func (w wrapTypesIdxr) EncodeText(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return encodeTextArray(ci, b, w.WrapIdxr, w.ts.FieldEncoder)
+}
+func (w wrapTypesIdxr) EncodeBinary(ci *pgtype.ConnInfo, b []byte) ([]byte, error) {
+	return encodeBinaryArray(ci, b, w.WrapIdxr, w.ts.FieldEncoder)
+}
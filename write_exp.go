@@ -18,6 +18,18 @@ import (
 func WriteExp(w *Writer, env exp.Env, e exp.Exp) error {
 	switch v := e.(type) {
 	case *exp.Sym:
+		if b, rest := w.bound(v); b != nil {
+			return w.writeBound(b, rest, v.Type())
+		}
+		if pt, ok := w.Translator.(PathTranslator); ok {
+			col, t, keys, err := pt.TranslatePath(w.Prog, env, v)
+			if err != nil {
+				return fmt.Errorf("symbol %q: %w", v.Sym, err)
+			}
+			if len(keys) > 0 {
+				return WritePath(w, col, t, keys, v.Type())
+			}
+		}
 		n, l, err := w.Translate(w.Prog, env, v)
 		if err != nil {
 			return fmt.Errorf("symbol %q: %w", v.Sym, err)
@@ -45,7 +57,6 @@ func WriteCall(w *Writer, env exp.Env, e *exp.Call) error {
 		return r.WriteCall(w, env, e)
 	}
 	// dyn and reduce are not supported
-	// TODO let and with might use common table expressions on a higher level
 	return fmt.Errorf("no writer for expression %s %s", key, e)
 }
 
@@ -56,7 +67,7 @@ type callWriter interface {
 var exprWriterMap map[string]callWriter
 
 func init() {
-	// TODO think about std specs dot let mut append fold as well as extlib specs
+	// TODO think about extlib specs
 	exprWriterMap = map[string]callWriter{
 		"or":  writeLogic{" OR ", false, PrecOr},
 		"and": writeLogic{" AND ", false, PrecAnd},
@@ -65,45 +76,62 @@ func init() {
 		// I found no better way for sql expression to fail when resolved but not otherwise.
 		// Sadly we cannot transport any failure message, but it suffices, because this is
 		// only meant to be a test helper.
-		"err":   writeRaw{".321/0", PrecCmp}, // 3..2..1..boom!
-		"add":   writeArith{" + ", PrecAdd},
-		"sub":   writeArith{" - ", PrecAdd},
-		"mul":   writeArith{" * ", PrecMul},
-		"div":   writeArith{" / ", PrecMul},
-		"rem":   writeArith{" % ", PrecMul},
-		"abs":   writeFunc(renderCall("ABS")),
-		"neg":   writeFunc(renderNeg),
-		"min":   writeFunc(renderCall("LEAST")),
-		"max":   writeFunc(renderCall("GREATEST")),
-		"eq":    writeEq{" = ", false},
-		"ne":    writeEq{" != ", false},
-		"lt":    writeCmp{" < "},
-		"ge":    writeCmp{" >= "},
-		"gt":    writeCmp{" > "},
-		"le":    writeCmp{" <= "},
-		"in":    writeIn{false},
-		"ni":    writeIn{true},
-		"equal": writeEq{" = ", true},
-		"if":    writeFunc(renderIf),
-		"swt":   writeFunc(renderSwt),
-		"df":    writeFunc(renderCall("COALESCE")),
-		"cat":   writeFunc(renderCall("CONCAT")),
-		"sep":   writeFunc(renderSep),
-		"xelf":  writeFunc(renderJSON), // json is valid xelf that postgres understands
-		"json":  writeFunc(renderJSON),
-		"make":  writeFunc(renderMake),
-		"len":   writeFunc(renderLen),
+		"err":     writeRaw{".321/0", PrecCmp}, // 3..2..1..boom!
+		"add":     writeArith{" + ", PrecAdd},
+		"sub":     writeFunc(renderSub), // casts times so that the difference is an interval
+		"mul":     writeArith{" * ", PrecMul},
+		"div":     writeArith{" / ", PrecMul},
+		"rem":     writeArith{" % ", PrecMul},
+		"abs":     writeFunc(renderCall("ABS")),
+		"neg":     writeFunc(renderNeg),
+		"min":     writeFunc(renderCall("LEAST")),
+		"max":     writeFunc(renderCall("GREATEST")),
+		"eq":      writeEq{" = ", false},
+		"ne":      writeEq{" != ", false},
+		"lt":      writeCmp{" < "},
+		"ge":      writeCmp{" >= "},
+		"gt":      writeCmp{" > "},
+		"le":      writeCmp{" <= "},
+		"in":      writeIn{false},
+		"ni":      writeIn{true},
+		"equal":   writeEq{" = ", true},
+		"if":      writeFunc(renderIf),
+		"swt":     writeFunc(renderSwt),
+		"df":      writeFunc(renderCall("COALESCE")),
+		"cat":     writeFunc(renderCall("CONCAT")),
+		"sep":     writeFunc(renderSep),
+		"xelf":    writeFunc(renderJSON), // json is valid xelf that postgres understands
+		"json":    writeFunc(renderJSON),
+		"make":    writeFunc(renderMake),
+		"len":     writeFunc(renderLen),
+		"let":     writeFunc(renderLet), // inlined or lifted into lateral subselects
+		"dot":     writeFunc(renderDot),
+		"append":  writeFunc(renderAppend), // for typed and jsonb arrays
+		"mut":     writeFunc(renderMut),    // for typed and jsonb arrays, json objects
+		"now":     writeFunc(renderNow),
+		"trunc":   writeFunc(renderTrunc),
+		"part":    writeFunc(renderPart),
+		"year":    writeFunc(renderField("year")),
+		"month":   writeFunc(renderField("month")),
+		"day":     writeFunc(renderField("day")),
+		"hour":    writeFunc(renderField("hour")),
+		"minute":  writeFunc(renderField("minute")),
+		"second":  writeFunc(renderField("second")),
+		"weekday": writeFunc(renderField("dow")),
+		"yearday": writeFunc(renderField("doy")),
+		"format":  writeFunc(renderFormat), // go reference layouts converted for to_char
+		"fn":      writeFunc(renderFn),
+		"range":   writeFunc(renderRange),       // using generate_series
+		"fold":    writeFunc(renderFold(false)), // for reducers with aggregate functions
+		"foldr":   writeFunc(renderFold(true)),
 		// dyn:      should already be resolved. lazily resolved dyns are disallowed.
-		// dot, let: we should be able to replace all occurrences of the declarations
-		//           otherwise we can use with ctes
-		// append:   for typed and jsonb arrays
-		// mut:      for typed and jsonb arrays, json object
-		// fn, fold, foldr, range: maybe possible as inline subquery?
 		"index": writeFunc(renderCall("strpos")),
 		// "last": (length($1) - strpos($1, $2))
-		"prefix":   writeLike{dir: 1}, // $1 like $2||'%'
-		"suffix":   writeLike{dir: 2}, // $1 like '%'||$2
-		"contains": writeLike{dir: 3}, // $1 like '%'||$2||'%'
+		"prefix":   writeLike{dir: 1},         // $1 like $2||'%'
+		"suffix":   writeLike{dir: 2},         // $1 like '%'||$2
+		"contains": writeFunc(renderContains), // $1 like '%'||$2||'%' or $1 @> $2
+		"overlaps": writeArith{" && ", PrecDef},
+		"within":   writeArith{" <@ ", PrecDef},
 		"upper":    writeFunc(renderCall("upper")),
 		"lower":    writeFunc(renderCall("lower")),
 		"trim":     writeFunc(renderCallOpt("trim", "both ' \t' from ")),
@@ -235,6 +263,15 @@ func renderNeg(w *Writer, env exp.Env, e *exp.Call) error {
 	w.Byte('-')
 	return WriteExp(w, env, e.Args[0])
 }
+func renderContains(w *Writer, env exp.Env, e *exp.Call) error {
+	// only ranges, multiranges, arrays and jsonb use the containment operator,
+	// everything else including unresolved types is matched as string
+	t := typ.Res(e.Args[0].Type())
+	if t.Kind&knd.Char == 0 && t.Kind&(knd.Idxr|knd.Keyr) != 0 {
+		return writeArith{" @> ", PrecDef}.WriteCall(w, env, e)
+	}
+	return writeLike{dir: 3}.WriteCall(w, env, e)
+}
 func renderLen(w *Writer, env exp.Env, e *exp.Call) error {
 	fst := e.Args[0]
 	if l, ok := fst.(*exp.Lit); ok {
@@ -609,11 +646,19 @@ func writeBool(w *Writer, env exp.Env, not bool, e exp.Exp) error {
 	return nil
 }
 
+// writeString returns the expression e written to a string. It writes through w with a swapped
+// buffer, so that external params are added to w and the current precedence is kept.
 func writeString(w *Writer, env exp.Env, e exp.Exp) (string, error) {
-	cc := *w
+	return captureString(w, func() error { return WriteExp(w, env, e) })
+}
+
+// captureString returns the output that f writes to w as string.
+func captureString(w *Writer, f func() error) (string, error) {
 	var b strings.Builder
-	cc.P = bfr.P{Writer: &b}
-	err := WriteExp(&cc, env, e)
+	orig := w.P.Writer
+	w.P.Writer = &b
+	err := f()
+	w.P.Writer = orig
 	if err != nil {
 		return "", err
 	}
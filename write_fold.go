@@ -0,0 +1,192 @@
+package dapgx
+
+import (
+	"fmt"
+
+	"xelf.org/xelf/cor"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/typ"
+)
+
+// foldAggs maps recognized reducers to the aggregate expression with the initial value and the
+// element as arguments. The sum is cast to the result type, because it is numeric for int8.
+var foldAggs = map[string]string{
+	"add": "%s + COALESCE(SUM(%s), 0)::%s",
+	"cat": "CONCAT(%s, string_agg(%s, ''%s))",
+	"min": "LEAST(%s, MIN(%s))",
+	"max": "GREATEST(%s, MAX(%s))",
+	"and": "(%s AND COALESCE(bool_and(%s), TRUE))",
+	"or":  "(%s OR COALESCE(bool_or(%s), FALSE))",
+}
+
+// renderFn returns an error, because functions have no sql value. They are only supported as
+// arguments of range, fold and foldr.
+func renderFn(w *Writer, env exp.Env, e *exp.Call) error {
+	return fmt.Errorf("fn can only be used as range mapper or fold reducer: %s", e)
+}
+
+// renderRange writes range expressions like (range n) or (range n (fn (mul _ 2))) as array or
+// jsonb list generated by generate_series.
+func renderRange(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) == 0 {
+		return fmt.Errorf("empty range expression")
+	}
+	n, err := writeString(w, env, e.Args[0])
+	if err != nil {
+		return err
+	}
+	if !trivial(e.Args[0]) {
+		n = fmt.Sprintf("(%s)", n)
+	}
+	name := w.Name("_r")
+	idx := name + ".i"
+	val := idx
+	if len(e.Args) > 1 && e.Args[1] != nil {
+		ps, body, err := fnParts(e.Args[1])
+		if err != nil {
+			return fmt.Errorf("range: %w", err)
+		}
+		bs := make([]*binding, 0, len(ps))
+		for _, p := range ps {
+			bs = append(bs, &binding{key: p, env: env, ref: idx, typ: typ.Int})
+		}
+		val, err = captureString(w, func() error { return writeScope(w, bs, body) })
+		if err != nil {
+			return err
+		}
+	}
+	src := fmt.Sprintf("generate_series(0, %s - 1) AS %s(i)", n, name)
+	if _, arr := isArray(e.Type()); arr {
+		return w.Fmt("ARRAY(SELECT %s FROM %s ORDER BY %s)", val, src, idx)
+	}
+	return w.Fmt("(SELECT COALESCE(jsonb_agg(%s ORDER BY %s), '[]'::jsonb) FROM %s)", val, idx, src)
+}
+
+// renderFold returns a writer for fold expressions like (fold list init add). Only reducers that
+// correspond to an aggregate function are supported. Elements are aggregated in list order for
+// fold and reverse order for foldr, which only matters for cat.
+func renderFold(right bool) writeFunc {
+	return func(w *Writer, env exp.Env, e *exp.Call) error {
+		if len(e.Args) < 3 {
+			return fmt.Errorf("fold expects list, initial value and reducer got %s", e)
+		}
+		op, err := reducerOp(e.Args[2])
+		if err != nil {
+			return err
+		}
+		agg := foldAggs[op]
+		if agg == "" {
+			return fmt.Errorf("fold reducer %s has no aggregate, expect add, cat, min, max, and or or", op)
+		}
+		list, err := writeString(w, env, e.Args[0])
+		if err != nil {
+			return err
+		}
+		init, err := writeString(w, env, e.Args[1])
+		if err != nil {
+			return err
+		}
+		if !trivial(e.Args[1]) {
+			init = fmt.Sprintf("(%s)", init)
+		}
+		ts, err := TypString(typ.Res(e.Type()))
+		if err != nil {
+			return err
+		}
+		name := w.Name("_f")
+		el := name + ".v"
+		var src string
+		if _, arr := isArray(e.Args[0].Type()); arr {
+			src = fmt.Sprintf("unnest(%s) WITH ORDINALITY AS %s(v, i)", list, name)
+		} else {
+			src = fmt.Sprintf("jsonb_array_elements_text(%s) WITH ORDINALITY AS %s(v, i)", list, name)
+			if op != "cat" {
+				el = fmt.Sprintf("%s::%s", el, ts)
+			}
+		}
+		var sel string
+		switch op {
+		case "cat":
+			ord := " ORDER BY " + name + ".i"
+			if right {
+				ord += " DESC"
+			}
+			sel = fmt.Sprintf(agg, init, el+"::text", ord)
+		case "add":
+			if ts == "jsonb" {
+				ts = "numeric"
+			}
+			sel = fmt.Sprintf(agg, init, el, ts)
+		default:
+			sel = fmt.Sprintf(agg, init, el)
+		}
+		return w.Fmt("(SELECT %s FROM %s)", sel, src)
+	}
+}
+
+// reducerOp returns the name of the built-in used as reducer e. That is either a spec reference
+// like add or a function like (fn (add _ .)) that only calls a built-in with its parameters.
+func reducerOp(e exp.Exp) (string, error) {
+	switch v := e.(type) {
+	case *exp.Sym:
+		return cor.Keyed(v.Sym), nil
+	case *exp.Lit:
+		if ref := typ.Res(v.Type()).Ref; ref != "" {
+			return cor.Keyed(ref), nil
+		}
+	case *exp.Call:
+		ps, body, err := fnParts(v)
+		if err != nil {
+			return "", err
+		}
+		if c, ok := body.(*exp.Call); ok && len(c.Args) > 0 {
+			var args []exp.Exp
+			each(c.Args, func(a exp.Exp) error {
+				args = append(args, a)
+				return nil
+			})
+			if len(args) == 2 && isParam(ps, args[0]) && isParam(ps, args[1]) {
+				return cor.Keyed(c.Sig.Ref), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("fold reducer %s cannot be translated, expect a built-in like add", e)
+}
+
+// fnParts returns the parameter names and body of the function expression e. The first
+// parameter can always be referred to as underscore.
+func fnParts(e exp.Exp) (ps []string, body exp.Exp, err error) {
+	c, ok := e.(*exp.Call)
+	if !ok || cor.Keyed(c.Sig.Ref) != "fn" {
+		return nil, nil, fmt.Errorf("expect fn expression got %s", e)
+	}
+	ps = []string{"_"}
+	each(c.Args, func(a exp.Exp) error {
+		if tag, ok := a.(*exp.Tag); ok && tag.Tag != "" {
+			ps = append(ps, tag.Tag)
+		} else {
+			body = a
+		}
+		return nil
+	})
+	if body == nil {
+		return nil, nil, fmt.Errorf("fn without body %s", e)
+	}
+	return ps, body, nil
+}
+
+func isParam(ps []string, e exp.Exp) bool {
+	s, ok := e.(*exp.Sym)
+	if !ok {
+		return false
+	}
+	if s.Sym == "." {
+		return true
+	}
+	for _, p := range ps {
+		if s.Sym == p {
+			return true
+		}
+	}
+	return false
+}
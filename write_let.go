@@ -0,0 +1,221 @@
+package dapgx
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/xelf/cor"
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/typ"
+)
+
+// binding is a let or dot declaration in scope of the writer. Bindings are inlined at their use,
+// unless they are used more than once and not trivial. Those are lifted into lateral subselects.
+type binding struct {
+	key string // declared name or "." for dot values
+	exp exp.Exp
+	env exp.Env
+	ref string // the sql reference if lifted or a parameter, otherwise empty to inline
+	typ typ.Type
+}
+
+// renderLet writes let expressions like (let a:x b:(add a 1) (mul b b)).
+func renderLet(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) < 2 {
+		return fmt.Errorf("let without body %s", e)
+	}
+	var bs []*binding
+	err := each(e.Args[:1], func(a exp.Exp) error {
+		tag, ok := a.(*exp.Tag)
+		if !ok {
+			return fmt.Errorf("let expects tag declarations got %T", a)
+		}
+		bs = append(bs, &binding{key: tag.Tag, exp: tag.Exp, env: env})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writeScope(w, bs, lastExp(e.Args[1:]))
+}
+
+// renderDot writes dot expressions like (dot x (add .a .b)), where the dot refers to x.
+func renderDot(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) < 2 {
+		return fmt.Errorf("dot without body %s", e)
+	}
+	b := &binding{key: ".", exp: e.Args[0], env: env}
+	return writeScope(w, []*binding{b}, lastExp(e.Args[1:]))
+}
+
+// writeScope writes body with the bindings bs in scope.
+func writeScope(w *Writer, bs []*binding, body exp.Exp) error {
+	if body == nil {
+		return fmt.Errorf("declarations without body")
+	}
+	n := len(w.binds)
+	defer func() { w.binds = w.binds[:n] }()
+	var lats []string
+	for i, b := range bs {
+		uses := countUses(b.key, body)
+		for _, o := range bs[i+1:] {
+			uses += countUses(b.key, o.exp)
+		}
+		if b.ref == "" && uses > 1 && !trivial(b.exp) {
+			str, err := writeString(w, b.env, b.exp)
+			if err != nil {
+				return err
+			}
+			name := w.Name("_l")
+			b.ref = name + ".v"
+			lats = append(lats, fmt.Sprintf("LATERAL (SELECT %s) AS %s(v)", str, name))
+		}
+		w.binds = append(w.binds, b)
+	}
+	if len(lats) == 0 {
+		return WriteExp(w, bs[len(bs)-1].env, body)
+	}
+	w.Fmt("(SELECT ")
+	err := WriteExp(w, bs[len(bs)-1].env, body)
+	if err != nil {
+		return err
+	}
+	return w.Fmt(" FROM %s)", strings.Join(lats, ", "))
+}
+
+// bound returns the innermost binding that s refers to and the remaining key path.
+func (w *Writer) bound(s *exp.Sym) (*binding, string) {
+	for i := len(w.binds) - 1; i >= 0; i-- {
+		b := w.binds[i]
+		if rest, ok := bindPath(b.key, s.Sym); ok {
+			return b, rest
+		}
+	}
+	return nil, ""
+}
+
+// bindPath returns whether the symbol sym refers to the binding key and the remaining path.
+func bindPath(key, sym string) (string, bool) {
+	if key == "." {
+		if sym == "." {
+			return "", true
+		}
+		if len(sym) > 1 && sym[0] == '.' && sym[1] != '.' {
+			return sym[1:], true
+		}
+		return "", false
+	}
+	if sym == key {
+		return "", true
+	}
+	if strings.HasPrefix(sym, key+".") {
+		return sym[len(key)+1:], true
+	}
+	return "", false
+}
+
+// writeBound writes the reference to binding b with the optional key path rest of type t.
+func (w *Writer) writeBound(b *binding, rest string, t typ.Type) error {
+	// the binding is written in its own scope
+	binds := w.binds
+	for i, o := range binds {
+		if o == b {
+			w.binds = binds[:i]
+			break
+		}
+	}
+	defer func() { w.binds = binds }()
+	bt := b.typ
+	if b.exp != nil {
+		bt = b.exp.Type()
+	}
+	if b.ref != "" {
+		ref := func() error { _, err := w.WriteString(b.ref); return err }
+		if rest == "" {
+			return ref()
+		}
+		return writePath(w, ref, bt, PathKeys(rest), t)
+	}
+	if rest == "" {
+		return WriteExp(w, b.env, b.exp)
+	}
+	base := func() error {
+		if trivial(b.exp) {
+			return WriteExp(w, b.env, b.exp)
+		}
+		w.Byte('(')
+		if err := WriteExp(w, b.env, b.exp); err != nil {
+			return err
+		}
+		return w.Byte(')')
+	}
+	return writePath(w, base, bt, PathKeys(rest), t)
+}
+
+// countUses returns the number of symbols in e, that refer to the binding key.
+func countUses(key string, e exp.Exp) (n int) {
+	switch v := e.(type) {
+	case *exp.Sym:
+		if _, ok := bindPath(key, v.Sym); ok {
+			n++
+		}
+	case *exp.Tag:
+		n += countUses(key, v.Exp)
+	case *exp.Tupl:
+		for _, el := range v.Els {
+			n += countUses(key, el)
+		}
+	case *exp.Call:
+		if k, ok := shadows(key, v); ok {
+			return n + k
+		}
+		for _, a := range v.Args {
+			n += countUses(key, a)
+		}
+	}
+	return n
+}
+
+// shadows returns whether the let or dot expression e redeclares key and if so the number of
+// uses in the declarations before the key is shadowed.
+func shadows(key string, e *exp.Call) (n int, ok bool) {
+	if len(e.Args) < 2 {
+		return 0, false
+	}
+	switch cor.Keyed(e.Sig.Ref) {
+	case "dot":
+		if key == "." {
+			return countUses(key, e.Args[0]), true
+		}
+	case "let":
+		each(e.Args[:1], func(a exp.Exp) error {
+			if ok {
+				return nil
+			}
+			n += countUses(key, a)
+			if tag, isTag := a.(*exp.Tag); isTag && tag.Tag == key {
+				ok = true
+			}
+			return nil
+		})
+	}
+	return n, ok
+}
+
+// trivial returns whether e is a literal or symbol, that can always be inlined.
+func trivial(e exp.Exp) bool {
+	switch e.(type) {
+	case *exp.Lit, *exp.Sym:
+		return true
+	}
+	return false
+}
+
+// lastExp returns the last expression of args with tuples flattened or nil.
+func lastExp(args []exp.Exp) (res exp.Exp) {
+	each(args, func(a exp.Exp) error {
+		res = a
+		return nil
+	})
+	return res
+}
@@ -46,6 +46,21 @@ func TypString(t typ.Type) (string, error) {
 	return "", fmt.Errorf("unexpected type %s", t)
 }
 
+// TypStringPrec returns the column type for t like TypString, but uses an exact numeric type
+// with the given precision and scale for number types if prec is positive.
+func TypStringPrec(t typ.Type, prec, scale int) (string, error) {
+	if prec > 0 {
+		switch t.Kind & knd.Any {
+		case knd.Num, knd.Int, knd.Real:
+			if scale > 0 {
+				return fmt.Sprintf("numeric(%d,%d)", prec, scale), nil
+			}
+			return fmt.Sprintf("numeric(%d)", prec), nil
+		}
+	}
+	return TypString(t)
+}
+
 // WriteLit renders the literal l to b or returns an error.
 func WriteLit(b *Writer, l *exp.Lit) error { return WriteVal(b, typ.Res(l.Type()), l.Val) }
 
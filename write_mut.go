@@ -0,0 +1,278 @@
+package dapgx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/typ"
+)
+
+// isArray returns whether values of type t are stored as native postgres arrays and the array
+// type. Other lists and all keyrs are stored as jsonb, the same decision that TypString makes.
+func isArray(t typ.Type) (string, bool) {
+	ts, err := TypString(typ.Res(t))
+	return ts, err == nil && strings.HasSuffix(ts, "[]")
+}
+
+// renderAppend writes append expressions like (append list a b) using array_append or the
+// concatenation operator for native arrays and jsonb lists.
+func renderAppend(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) == 0 {
+		return fmt.Errorf("empty append expression")
+	}
+	var els []exp.Exp
+	each(e.Args[1:], func(a exp.Exp) error {
+		els = append(els, a)
+		return nil
+	})
+	if len(els) == 0 {
+		return WriteExp(w, env, e.Args[0])
+	}
+	ts, arr := isArray(e.Args[0].Type())
+	defer w.Prec(PrecDef)()
+	if arr && len(els) == 1 {
+		w.Fmt("array_append(")
+		err := WriteExp(w, env, e.Args[0])
+		if err != nil {
+			return err
+		}
+		w.Fmt(", ")
+		err = WriteExp(w, env, els[0])
+		if err != nil {
+			return err
+		}
+		return w.Byte(')')
+	}
+	err := WriteExp(w, env, e.Args[0])
+	if err != nil {
+		return err
+	}
+	if arr {
+		w.Fmt(" || ARRAY[")
+	} else {
+		w.Fmt(" || jsonb_build_array(")
+	}
+	err = writeEach(w, env, els, ", ")
+	if err != nil {
+		return err
+	}
+	if arr {
+		return w.Fmt("]::%s", ts)
+	}
+	return w.Byte(')')
+}
+
+// renderMut writes mut expressions like (mut obj a:1 .b.0:2) using jsonb_set for jsonb values
+// and array slices for native arrays, that can only be changed by index.
+// Arguments without tag are merged or concatenated.
+func renderMut(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) == 0 {
+		return fmt.Errorf("empty mut expression")
+	}
+	defer w.Prec(PrecDef)()
+	_, arr := isArray(e.Args[0].Type())
+	cur, err := writeString(w, env, e.Args[0])
+	if err != nil {
+		return err
+	}
+	simple := trivial(e.Args[0])
+	err = each(e.Args[1:], func(a exp.Exp) error {
+		tag, ok := a.(*exp.Tag)
+		if !ok {
+			val, err := writeString(w, env, a)
+			if err != nil {
+				return err
+			}
+			cur, simple = fmt.Sprintf("%s || %s", cur, val), false
+			return nil
+		}
+		keys := strings.Split(strings.TrimPrefix(tag.Tag, "."), ".")
+		if !arr {
+			val, err := jsonbString(w, env, tag.Exp)
+			if err != nil {
+				return err
+			}
+			cur, simple = fmt.Sprintf("jsonb_set(%s, %s, %s)", cur, Quote(pathArray(keys)), val), true
+			return nil
+		}
+		idx, err := strconv.Atoi(keys[0])
+		if err != nil || idx < 0 || len(keys) > 1 {
+			return fmt.Errorf("mut of array expects index got %s", tag.Tag)
+		}
+		val, err := writeString(w, env, tag.Exp)
+		if err != nil {
+			return err
+		}
+		if !simple {
+			cur = fmt.Sprintf("(%s)", cur)
+		}
+		cur, simple = fmt.Sprintf("%[1]s[:%[2]d] || %[3]s || %[1]s[%[4]d:]", cur, idx, val, idx+2), false
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteString(cur)
+	return err
+}
+
+// jsonbString returns the expression e written as jsonb value.
+func jsonbString(w *Writer, env exp.Env, e exp.Exp) (string, error) {
+	if l, ok := e.(*exp.Lit); ok {
+		return captureString(w, func() error { return writeJSONB(w, l.Val) })
+	}
+	str, err := writeString(w, env, e)
+	if err != nil {
+		return "", err
+	}
+	t := typ.Res(e.Type())
+	ts, err := TypString(t)
+	if err != nil || ts == "jsonb" {
+		return fmt.Sprintf("to_jsonb(%s)", str), nil
+	}
+	return fmt.Sprintf("to_jsonb(CAST(%s AS %s))", str, ts), nil
+}
+
+// pathArray returns keys as postgres text array literal for jsonb path functions.
+func pathArray(keys []string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(k))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// writePath writes the access of the key path into the value of type bt written by base, that
+// must not need parenthesis. Native arrays can only be indexed, negative indices count from the
+// end. The result has type t.
+func writePath(w *Writer, base func() error, bt typ.Type, keys []string, t typ.Type) error {
+	if _, arr := isArray(bt); !arr {
+		return writeJSONPath(w, base, keys, t)
+	}
+	idx, err := strconv.Atoi(keys[0])
+	if err != nil || len(keys) > 1 {
+		return fmt.Errorf("path into array expects index got %s", strings.Join(keys, "."))
+	}
+	if err = base(); err != nil {
+		return err
+	}
+	if idx >= 0 {
+		return w.Fmt("[%d]", idx+1)
+	}
+	w.Fmt("[array_upper(")
+	if err = base(); err != nil {
+		return err
+	}
+	if idx == -1 {
+		return w.Fmt(", 1)]")
+	}
+	return w.Fmt(", 1)%d]", idx+1)
+}
+
+// writeJSONPath writes a jsonb path access of keys into the value written by base, that must
+// not need parenthesis. The result is cast to the column type of t, unless it is a container.
+// Paths with a wildcard key, that selects each list element, use jsonb_path_query_array.
+func writeJSONPath(w *Writer, base func() error, keys []string, t typ.Type) error {
+	for _, k := range keys {
+		if k == "*" {
+			return writeJSONPathQuery(w, base, keys)
+		}
+	}
+	t = typ.Res(t)
+	ts, err := TypString(t)
+	if err != nil {
+		return err
+	}
+	cast := ts != "jsonb" && t.Kind&knd.Data != knd.Data
+	if cast {
+		w.Byte('(')
+	}
+	if err = base(); err != nil {
+		return err
+	}
+	for i, k := range keys {
+		op := "->"
+		if cast && i == len(keys)-1 {
+			op = "->>"
+		}
+		if _, err := strconv.Atoi(k); err == nil {
+			w.Fmt("%s%s", op, k)
+		} else {
+			w.Fmt("%s%s", op, Quote(k))
+		}
+	}
+	if cast {
+		return w.Fmt(")::%s", ts)
+	}
+	return nil
+}
+
+func writeJSONPathQuery(w *Writer, base func() error, keys []string) error {
+	defer w.Prec(PrecDef)()
+	w.Fmt("jsonb_path_query_array(")
+	if err := base(); err != nil {
+		return err
+	}
+	w.Fmt(", ")
+	WriteQuote(w, JSONPath(keys))
+	return w.Byte(')')
+}
+
+// JSONPath returns keys as sql/json path expression. Wildcard keys select all list elements
+// and negative indices count from the end.
+func JSONPath(keys []string) string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, k := range keys {
+		if k == "*" {
+			b.WriteString("[*]")
+			continue
+		}
+		if i, err := strconv.Atoi(k); err == nil {
+			switch {
+			case i >= 0:
+				fmt.Fprintf(&b, "[%d]", i)
+			case i == -1:
+				b.WriteString("[last]")
+			default:
+				fmt.Fprintf(&b, "[last%d]", i+1)
+			}
+			continue
+		}
+		b.WriteString(`."`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(k))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// WritePath writes the access of the key path into column col of type ct. The result is cast
+// to the column type of t for scalar types, so that it can be compared and ordered.
+func WritePath(w *Writer, col string, ct typ.Type, keys []string, t typ.Type) error {
+	if len(keys) == 0 {
+		return WriteIdent(w, col)
+	}
+	base := func() error { _, err := w.WriteString(col); return err }
+	return writePath(w, base, ct, keys, t)
+}
+
+// PathKeys splits a relative symbol path like .extra.color or .items/name into keys. Each
+// slash separator selects from all list elements and is returned as wildcard key.
+func PathKeys(path string) []string {
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil
+	}
+	path = strings.ReplaceAll(path, "/", ".*.")
+	return strings.Split(path, ".")
+}
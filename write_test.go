@@ -6,7 +6,8 @@ import (
 
 	"xelf.org/xelf/cor"
 	"xelf.org/xelf/exp"
-	"xelf.org/xelf/lib"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lib/extlib"
 	"xelf.org/xelf/lit"
 	"xelf.org/xelf/typ"
 )
@@ -39,6 +40,7 @@ func TestRender(t *testing.T) {
 		{`(in x t [4 5])`, `x = ANY(t) OR x IN (4, 5)`},
 		{`(ni x t [4 5])`, `x != ALL(t) AND x NOT IN (4, 5)`},
 		{`(cat 'hell' 'o W' 'orld')`, `CONCAT('hell', 'o W', 'orld')`},
+		{`(contains v 'a')`, `v like '%'||replace(replace(replace('a', '\', '\\'), '_', '\_'), '%', '\%')||'%'`},
 		{`(sep ' | ' 'hell' 'o W' 'orld')`, `CONCAT('hell', ' | ', 'o W', ' | ', 'orld')`},
 		{`(equal x 1)`, `(x = 1 AND pg_typeof(x) = pg_typeof(1))`},
 		{`(gt x y 1)`, `x > y AND y > 1`},
@@ -60,14 +62,56 @@ func TestRender(t *testing.T) {
 		{`(len s)`, `jsonb_array_length(s)`},
 		{`(len t)`, `array_length(t, 1)`},
 		{`(len d)`, `(SELECT COUNT(*) FROM jsonb_object_keys(d))`},
+		{`(let a:x (add a 1))`, `x + 1`},
+		{`(let a:(add x 1) (mul a 2))`, `(x + 1) * 2`},
+		{`(let a:(add x 1) (mul a a))`, `(SELECT _l1.v * _l1.v FROM LATERAL (SELECT x + 1) AS _l1(v))`},
+		{`(let a:(add x p) (mul a a))`, `(SELECT _l1.v * _l1.v FROM LATERAL (SELECT x + $1) AS _l1(v))`},
+		{`(let a:(add x 1) (let a:y (mul a a)))`, `y * y`},
+		{`(dot d (cat .name))`, `CONCAT((d->>'name')::text)`},
+		{`(dot d (cat .extra.color))`, `CONCAT((d->'extra'->>'color')::text)`},
+		{`(let a:d (cat a.tags.-1))`, `CONCAT((d->'tags'->>-1)::text)`},
+		{`(append t 4)`, `array_append(t, 4)`},
+		{`(append t 4 5)`, `t || ARRAY[4, 5]::int8[]`},
+		{`(append s 4)`, `s || jsonb_build_array(4)`},
+		{`(mut d a:1)`, `jsonb_set(d, '{"a"}', '1'::jsonb)`},
+		{`(mut s .0:x)`, `jsonb_set(s, '{"0"}', to_jsonb(CAST(x AS int8)))`},
+		{`(mut t .1:x)`, `t[:1] || x || t[3:]`},
+		{`(mut t .1:p)`, `t[:1] || $1 || t[3:]`},
+		{`(mut d a:p)`, `jsonb_set(d, '{"a"}', to_jsonb(CAST($1 AS int8)))`},
+		{`(append t p)`, `array_append(t, $1)`},
+		{`(let a:t (add a.0 a.-1))`, `t[1] + t[array_upper(t, 1)]`},
+		{`(range 3)`, `ARRAY(SELECT _r1.i FROM generate_series(0, 3 - 1) AS _r1(i) ORDER BY _r1.i)`},
+		{`(fold t 1 add)`, `(SELECT 1 + COALESCE(SUM(_f1.v), 0)::int8 FROM unnest(t) WITH ORDINALITY AS _f1(v, i))`},
+		{`(fold t p max)`, `(SELECT GREATEST($1, MAX(_f1.v)) FROM unnest(t) WITH ORDINALITY AS _f1(v, i))`},
+		{`(range p (fn (mul _ 2)))`, `ARRAY(SELECT _r1.i * 2 FROM generate_series(0, $1 - 1) AS _r1(i) ORDER BY _r1.i)`},
+		{`(foldr t 'x' (fn (cat _ .)))`, `(SELECT CONCAT('x', string_agg(_f1.v::text, '' ORDER BY _f1.i DESC)) ` +
+			`FROM unnest(t) WITH ORDINALITY AS _f1(v, i))`},
+		{`(now)`, `now()`},
+		{`(trunc ts 'month')`, `date_trunc('month', ts)`},
+		{`(part ts 'hour')`, `extract(hour from ts)::int8`},
+		{`(part ts 'second')`, `trunc(extract(second from ts))::int8`},
+		{`(year ts)`, `extract(year from ts)::int8`},
+		{`(month ts)`, `extract(month from ts)::int8`},
+		{`(day ts)`, `extract(day from ts)::int8`},
+		{`(hour ts)`, `extract(hour from ts)::int8`},
+		{`(minute ts)`, `extract(minute from ts)::int8`},
+		{`(second ts)`, `trunc(extract(second from ts))::int8`},
+		{`(weekday ts)`, `extract(dow from ts)::int8`},
+		{`(yearday ts)`, `extract(doy from ts)::int8`},
+		{`(format ts '2006-01-02')`, `to_char(ts, 'YYYY-MM-DD')`},
+		{`(sub ts tu)`, `ts::timestamptz - tu::timestamptz`},
+		{`(sub (add ts (span '1h')) tu)`, `(ts + '1h'::interval)::timestamptz - tu::timestamptz`},
+		{`(sub ts (span '1h'))`, `ts - '1h'::interval`},
 	}
-	env := &unresEnv{Par: lib.Std}
+	env := &unresEnv{Par: extlib.Std}
 	env.add(typ.Bool, "a", "b", "c")
 	env.add(typ.Str, "v", "w")
 	env.add(typ.Int, "x", "y")
 	env.add(typ.Dict, "d")
 	env.add(typ.List, "s")
 	env.add(typ.ListOf(typ.Int), "t")
+	env.add(typ.Time, "ts", "tu")
+	env.add(typ.Int, "p")
 	for _, test := range tests {
 		ast, err := exp.Parse(test.el)
 		if err != nil {
@@ -81,7 +125,7 @@ func TestRender(t *testing.T) {
 			continue
 		}
 		var b strings.Builder
-		w := NewWriter(&b, nil, p, ExpEnv{})
+		w := NewWriter(&b, nil, p, extEnv{"p"})
 		err = WriteExp(w, p, el)
 		if err != nil {
 			t.Errorf("render %s err: %+v", test.el, err)
@@ -91,6 +135,56 @@ func TestRender(t *testing.T) {
 		if got != test.want {
 			t.Errorf("%s want %s got %s", el, test.want, got)
 		}
+		if want := strings.Contains(test.want, "$1"); want != (len(w.Params) == 1) {
+			t.Errorf("%s want one param got %v", el, w.Params)
+		}
+	}
+}
+
+// extEnv translates the listed symbols as external params and all others like ExpEnv.
+type extEnv []string
+
+func (e extEnv) Translate(p *exp.Prog, env exp.Env, s *exp.Sym) (string, lit.Val, error) {
+	for _, ext := range e {
+		if s.Sym == ext {
+			return "", nil, External
+		}
+	}
+	return ExpEnv{}.Translate(p, env, s)
+}
+
+func TestRenderRangeOps(t *testing.T) {
+	// ranges are decoded as objects
+	rng := typ.Type{Kind: knd.Obj}
+	tests := []struct {
+		ref  string
+		t    typ.Type
+		want string
+	}{
+		{"overlaps", rng, `$1 && $2`},
+		{"within", rng, `$1 <@ $2`},
+		{"contains", rng, `$1 @> $2`},
+		{"contains", typ.Str, `$1 like '%'||replace(replace(replace($2, '\', '\\'), '_', '\_'), '%', '\%')||'%'`},
+	}
+	for _, test := range tests {
+		// the range operator specs are provided by the program env of the caller,
+		// so we render the resolved call directly
+		e := &exp.Call{Sig: typ.Type{Ref: test.ref}, Args: []exp.Exp{
+			&exp.Sym{Sym: "a", Res: test.t}, &exp.Sym{Sym: "b", Res: test.t},
+		}}
+		var b strings.Builder
+		w := NewWriter(&b, nil, nil, extEnv{"a", "b"})
+		err := WriteExp(w, nil, e)
+		if err != nil {
+			t.Errorf("render %s err: %v", test.ref, err)
+			continue
+		}
+		if got := b.String(); got != test.want {
+			t.Errorf("%s want %s got %s", test.ref, test.want, got)
+		}
+		if len(w.Params) != 2 {
+			t.Errorf("%s want two params got %v", test.ref, w.Params)
+		}
 	}
 }
 
@@ -115,3 +209,41 @@ func (e *unresEnv) Lookup(s *exp.Sym, p cor.Path, eval bool) (lit.Val, error) {
 	}
 	return e.Par.Lookup(s, p, eval)
 }
+
+func TestPgTimeFormat(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{"2006-01-02", "YYYY-MM-DD"},
+		{"2006-01-02 15:04:05.000", "YYYY-MM-DD HH24:MI:SS.MS"},
+		{"Jan 2, 2006 at 3:04pm", `Mon FMDD, YYYY "at" FMHH12:MIam`},
+		{"Monday, 02-Jan-06 15:04:05 MST", "FMDay, DD-Mon-YY HH24:MI:SS TZ"},
+		{"2006-01-02T15:04:05Z07:00", `YYYY-MM-DD"T"HH24:MI:SSOF`},
+	}
+	for _, test := range tests {
+		if got := PgTimeFormat(test.layout); got != test.want {
+			t.Errorf("%s want %s got %s", test.layout, test.want, got)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{".extra", `$."extra"`},
+		{".extra.color", `$."extra"."color"`},
+		{".tags.0", `$."tags"[0]`},
+		{".tags.-1", `$."tags"[last]`},
+		{".tags.-3", `$."tags"[last-2]`},
+		{".items/name", `$."items"[*]."name"`},
+		{`.a"b`, `$."a\"b"`},
+	}
+	for _, test := range tests {
+		if got := JSONPath(PathKeys(test.path)); got != test.want {
+			t.Errorf("%s want %s got %s", test.path, test.want, got)
+		}
+	}
+}
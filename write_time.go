@@ -0,0 +1,248 @@
+package dapgx
+
+import (
+	"fmt"
+	"strings"
+
+	"xelf.org/xelf/exp"
+	"xelf.org/xelf/knd"
+	"xelf.org/xelf/lit"
+	"xelf.org/xelf/typ"
+)
+
+// timeUnits are the units accepted by date_trunc and time part fields accepted by extract.
+var (
+	timeUnits = []string{"microseconds", "milliseconds", "second", "minute", "hour", "day", "week",
+		"month", "quarter", "year", "decade", "century", "millennium"}
+	timeFields = []string{"century", "day", "decade", "dow", "doy", "epoch", "hour", "isodow",
+		"isoyear", "microseconds", "millennium", "milliseconds", "minute", "month", "quarter",
+		"second", "timezone", "week", "year"}
+)
+
+func renderNow(w *Writer, env exp.Env, e *exp.Call) error {
+	return w.Fmt("now()")
+}
+
+// renderTrunc writes (trunc t 'month') as date_trunc('month', t).
+func renderTrunc(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) < 2 {
+		return fmt.Errorf("trunc expects time and unit got %s", e)
+	}
+	defer w.Prec(PrecDef)()
+	w.Fmt("date_trunc(")
+	err := writeTimeUnit(w, env, e.Args[1], timeUnits)
+	if err != nil {
+		return err
+	}
+	w.Fmt(", ")
+	err = WriteExp(w, env, e.Args[0])
+	if err != nil {
+		return err
+	}
+	return w.Byte(')')
+}
+
+// renderPart writes (part t 'year') as extract(year from t) cast to the result type. Parts that
+// are not literal use date_part instead.
+func renderPart(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) < 2 {
+		return fmt.Errorf("part expects time or span and field got %s", e)
+	}
+	if l, ok := e.Args[1].(*exp.Lit); ok {
+		f, err := timeUnit(l, timeFields)
+		if err != nil {
+			return err
+		}
+		return writeExtract(w, env, e.Args[0], f, nil, e.Type())
+	}
+	return writeExtract(w, env, e.Args[0], "", e.Args[1], e.Type())
+}
+
+// renderField returns a writer for helpers like (year t) that extract the time field.
+func renderField(field string) writeFunc {
+	return func(w *Writer, env exp.Env, e *exp.Call) error {
+		if len(e.Args) < 1 {
+			return fmt.Errorf("%s expects a time got %s", field, e)
+		}
+		return writeExtract(w, env, e.Args[0], field, nil, e.Type())
+	}
+}
+
+// writeExtract writes the extraction of field or the dynamic field dyn from arg.
+// Integer results of fractional fields are truncated first, because the cast would round.
+func writeExtract(w *Writer, env exp.Env, arg exp.Exp, field string, dyn exp.Exp, t typ.Type) error {
+	defer w.Prec(PrecDef)()
+	t = typ.Res(t)
+	isInt := t.Kind&knd.Int != 0
+	frac := isInt && (dyn != nil || field == "second" || field == "milliseconds" || field == "epoch")
+	if frac {
+		w.Fmt("trunc(")
+	}
+	if dyn == nil {
+		w.Fmt("extract(%s from ", field)
+	} else {
+		w.Fmt("date_part(")
+		err := WriteExp(w, env, dyn)
+		if err != nil {
+			return err
+		}
+		w.Fmt(", ")
+	}
+	err := WriteExp(w, env, arg)
+	if err != nil {
+		return err
+	}
+	w.Byte(')')
+	if frac {
+		w.Byte(')')
+	}
+	if isInt {
+		return w.Fmt("::int8")
+	}
+	return w.Fmt("::float8")
+}
+
+func writeTimeUnit(w *Writer, env exp.Env, e exp.Exp, units []string) error {
+	if l, ok := e.(*exp.Lit); ok {
+		u, err := timeUnit(l, units)
+		if err != nil {
+			return err
+		}
+		return WriteQuote(w, u)
+	}
+	return WriteExp(w, env, e)
+}
+
+func timeUnit(l *exp.Lit, units []string) (string, error) {
+	s, err := lit.ToStr(l.Val)
+	if err != nil {
+		return "", err
+	}
+	u := strings.ToLower(string(s))
+	for _, k := range units {
+		if u == k || u+"s" == k {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown time unit %q, expect one of %s", u, strings.Join(units, ", "))
+}
+
+// renderFormat writes (format t '2006-01-02') as to_char(t, 'YYYY-MM-DD'). The format must be a
+// literal go reference layout, that is converted to a postgres template pattern.
+func renderFormat(w *Writer, env exp.Env, e *exp.Call) error {
+	if len(e.Args) < 2 {
+		return fmt.Errorf("format expects time and layout got %s", e)
+	}
+	l, ok := e.Args[1].(*exp.Lit)
+	if !ok {
+		return fmt.Errorf("format layout must be a literal got %s", e.Args[1])
+	}
+	layout, err := lit.ToStr(l.Val)
+	if err != nil {
+		return err
+	}
+	defer w.Prec(PrecDef)()
+	w.Fmt("to_char(")
+	err = WriteExp(w, env, e.Args[0])
+	if err != nil {
+		return err
+	}
+	w.Fmt(", ")
+	WriteQuote(w, PgTimeFormat(string(layout)))
+	return w.Byte(')')
+}
+
+// renderSub writes subtractions. Times are cast to timestamptz, so that the difference of two
+// times is always an interval, that is decoded as span.
+func renderSub(w *Writer, env exp.Env, e *exp.Call) error {
+	var args []exp.Exp
+	each(e.Args, func(a exp.Exp) error {
+		args = append(args, a)
+		return nil
+	})
+	times := 0
+	for _, a := range args {
+		if typ.Res(a.Type()).Kind&knd.Data == knd.Time {
+			times++
+		}
+	}
+	if times < 2 {
+		return writeArith{" - ", PrecAdd}.WriteCall(w, env, e)
+	}
+	defer w.Prec(PrecAdd)()
+	for i, a := range args {
+		if i > 0 {
+			w.Fmt(" - ")
+		}
+		if _, ok := a.(*exp.Lit); ok || typ.Res(a.Type()).Kind&knd.Data != knd.Time {
+			err := WriteExp(w, env, a)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if !trivial(a) {
+			w.Byte('(')
+		}
+		err := WriteExp(w, env, a)
+		if err != nil {
+			return err
+		}
+		if !trivial(a) {
+			w.Byte(')')
+		}
+		w.Fmt("::timestamptz")
+	}
+	return nil
+}
+
+// layoutTokens maps go reference layout elements to postgres template patterns, longer first.
+var layoutTokens = []struct{ goes, pg string }{
+	{"January", "FMMonth"}, {"Monday", "FMDay"}, {"Jan", "Mon"}, {"Mon", "Dy"},
+	{"Z07:00", "OF"}, {"-07:00", "OF"}, {"-0700", "OF"}, {"MST", "TZ"},
+	{".000000", ".US"}, {".000", ".MS"}, {"2006", "YYYY"}, {"002", "DDD"},
+	{"01", "MM"}, {"02", "DD"}, {"_2", "FMDD"}, {"03", "HH12"}, {"04", "MI"}, {"05", "SS"},
+	{"06", "YY"}, {"15", "HH24"}, {"PM", "AM"}, {"pm", "am"},
+	{"1", "FMMM"}, {"2", "FMDD"}, {"3", "FMHH12"}, {"4", "FMMI"}, {"5", "FMSS"},
+}
+
+// PgTimeFormat converts the go reference layout to a postgres template pattern for to_char.
+// Other letters are quoted so that postgres does not read them as patterns.
+func PgTimeFormat(layout string) string {
+	var b strings.Builder
+	var quoted bool
+	for len(layout) > 0 {
+		var tok string
+		for _, t := range layoutTokens {
+			if strings.HasPrefix(layout, t.goes) {
+				tok = t.pg
+				layout = layout[len(t.goes):]
+				break
+			}
+		}
+		if tok == "" {
+			c := layout[0]
+			layout = layout[1:]
+			letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			if letter != quoted {
+				b.WriteByte('"')
+				quoted = letter
+			}
+			if c == '"' {
+				b.WriteString(`\"`)
+			} else {
+				b.WriteByte(c)
+			}
+			continue
+		}
+		if quoted {
+			b.WriteByte('"')
+			quoted = false
+		}
+		b.WriteString(tok)
+	}
+	if quoted {
+		b.WriteByte('"')
+	}
+	return b.String()
+}
@@ -20,6 +20,8 @@ type Writer struct {
 	Prog *exp.Prog
 	Translator
 	Params []Param
+	binds  []*binding
+	names  map[string]bool
 }
 
 type Param struct {
@@ -33,8 +35,31 @@ func NewWriter(b bfr.Writer, pr *dom.Project, p *exp.Prog, t Translator) *Writer
 		P:       bfr.P{Writer: b, Tab: "\t"},
 		Project: pr,
 		Header:  "-- generated code\n\n",
-	}, p, t, nil}
+	}, p, t, nil, nil, make(map[string]bool)}
 }
+
+// Reserve marks names as used, so that generated names do not clash with them.
+// Query generators should reserve their table aliases.
+func (w *Writer) Reserve(names ...string) {
+	if w.names == nil {
+		w.names = make(map[string]bool)
+	}
+	for _, n := range names {
+		w.names[n] = true
+	}
+}
+
+// Name returns and reserves a new name starting with prefix, that is not yet used.
+func (w *Writer) Name(prefix string) string {
+	for i := 1; ; i++ {
+		n := fmt.Sprintf("%s%d", prefix, i)
+		if !w.names[n] {
+			w.Reserve(n)
+			return n
+		}
+	}
+}
+
 func (w *Writer) Translate(p *exp.Prog, env exp.Env, s *exp.Sym) (string, lit.Val, error) {
 	for i, p := range w.Params {
 		// TODO better way to identify a reference, maybe in another env
@@ -60,6 +85,13 @@ type Translator interface {
 	Translate(*exp.Prog, exp.Env, *exp.Sym) (string, lit.Val, error)
 }
 
+// PathTranslator is a translator that resolves symbols with a key path to a column of type t and
+// the remaining keys, that are written as array index or jsonb access. Symbols without remaining
+// keys are translated as usual.
+type PathTranslator interface {
+	TranslatePath(*exp.Prog, exp.Env, *exp.Sym) (col string, t typ.Type, keys []string, err error)
+}
+
 type ExpEnv struct{}
 
 func (ee ExpEnv) Translate(p *exp.Prog, env exp.Env, s *exp.Sym) (n string, v lit.Val, err error) {